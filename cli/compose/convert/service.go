@@ -0,0 +1,41 @@
+package convert
+
+import (
+	"github.com/DeJeune/sudocker/cli/compose/types"
+	"github.com/DeJeune/sudocker/cli/opts"
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/capabilites"
+	"github.com/pkg/errors"
+)
+
+// HostConfig 将服务中 sudocker 支持的容器选项转换为HostConfig，选项的解析与 run、create 的命令行参数一致
+func HostConfig(service types.ServiceConfig) (*config.HostConfig, error) {
+	restartPolicy, err := convertRestartPolicy(service.Restart)
+	if err != nil {
+		return nil, err
+	}
+	// 与 --cap-add、--cap-drop 一样统一capability的写法
+	capAdd, err := capabilites.NormalizeCapabilities(service.CapAdd)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid cap_add")
+	}
+	capDrop, err := capabilites.NormalizeCapabilities(service.CapDrop)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid cap_drop")
+	}
+	return &config.HostConfig{
+		CapAdd:        capAdd,
+		CapDrop:       capDrop,
+		Privileged:    service.Privileged,
+		RestartPolicy: restartPolicy,
+	}, nil
+}
+
+// convertRestartPolicy 转换服务的 restart 选项，写法与 --restart 相同
+func convertRestartPolicy(restart string) (config.RestartPolicy, error) {
+	policy, err := opts.ParseRestartPolicy(restart)
+	if err != nil {
+		return config.RestartPolicy{}, errors.WithMessagef(err, "invalid restart policy %q", restart)
+	}
+	return policy, nil
+}
@@ -0,0 +1,50 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/DeJeune/sudocker/cli/compose/types"
+	"github.com/DeJeune/sudocker/runtime/config"
+)
+
+func TestHostConfigRestartPolicy(t *testing.T) {
+	hostConfig, err := HostConfig(types.ServiceConfig{Restart: "on-failure:3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config.RestartPolicy{Name: config.RestartPolicyOnFailure, MaximumRetryCount: 3}
+	if hostConfig.RestartPolicy != expected {
+		t.Errorf("expected %+v but %+v got", expected, hostConfig.RestartPolicy)
+	}
+
+	if _, err := HostConfig(types.ServiceConfig{Restart: "sometimes"}); err == nil {
+		t.Error("expected an error for an unknown restart policy")
+	}
+}
+
+func TestHostConfigCapabilities(t *testing.T) {
+	hostConfig, err := HostConfig(types.ServiceConfig{CapAdd: []string{"net_admin"}, CapDrop: []string{"ALL"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hostConfig.CapAdd) != 1 || hostConfig.CapAdd[0] != "CAP_NET_ADMIN" {
+		t.Errorf("expected [CAP_NET_ADMIN] but %v got", hostConfig.CapAdd)
+	}
+	if len(hostConfig.CapDrop) != 1 || hostConfig.CapDrop[0] != "ALL" {
+		t.Errorf("expected [ALL] but %v got", hostConfig.CapDrop)
+	}
+
+	if _, err := HostConfig(types.ServiceConfig{CapAdd: []string{"CAP_BOGUS"}}); err == nil {
+		t.Error("expected an error for an unknown capability")
+	}
+}
+
+func TestHostConfigPrivileged(t *testing.T) {
+	hostConfig, err := HostConfig(types.ServiceConfig{Privileged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hostConfig.Privileged {
+		t.Error("expected a privileged host config")
+	}
+}
@@ -24,8 +24,6 @@ var UnsupportedProperties = []string{
 	"mac_address",
 	"network_mode",
 	"pid",
-	"privileged",
-	"restart",
 	"security_opt",
 	"shm_size",
 	"userns_mode",
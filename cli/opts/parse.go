@@ -1,8 +1,12 @@
 package opts
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/DeJeune/sudocker/runtime/config"
 )
 
 func ReadKVStrings(files []string, override []string) ([]string, error) {
@@ -50,3 +54,27 @@ func ConvertKVStringsToMapWithNil(values []string) map[string]*string {
 	}
 	return result
 }
+
+// ParseRestartPolicy 解析 --restart 参数，格式为 no、always、unless-stopped 或 on-failure[:max-retries]
+func ParseRestartPolicy(policy string) (config.RestartPolicy, error) {
+	if policy == "" {
+		return config.RestartPolicy{Name: config.RestartPolicyDisabled}, nil
+	}
+
+	name, count, hasColon := strings.Cut(policy, ":")
+	if hasColon && name == "" {
+		return config.RestartPolicy{}, fmt.Errorf("invalid restart policy format: no policy provided before colon")
+	}
+	p := config.RestartPolicy{Name: config.RestartPolicyMode(name)}
+	if count != "" {
+		maxRetries, err := strconv.Atoi(count)
+		if err != nil {
+			return config.RestartPolicy{}, fmt.Errorf("invalid restart policy format: maximum retry count must be an integer")
+		}
+		p.MaximumRetryCount = maxRetries
+	}
+	if err := config.ValidateRestartPolicy(p); err != nil {
+		return config.RestartPolicy{}, err
+	}
+	return p, nil
+}
@@ -0,0 +1,54 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	valid := map[string]config.RestartPolicy{
+		"":               {Name: config.RestartPolicyDisabled},
+		"no":             {Name: config.RestartPolicyDisabled},
+		"always":         {Name: config.RestartPolicyAlways},
+		"unless-stopped": {Name: config.RestartPolicyUnlessStopped},
+		"on-failure":     {Name: config.RestartPolicyOnFailure},
+		"on-failure:3":   {Name: config.RestartPolicyOnFailure, MaximumRetryCount: 3},
+	}
+	for input, expected := range valid {
+		policy, err := ParseRestartPolicy(input)
+		if err != nil {
+			t.Errorf("parse %q: unexpected error %v", input, err)
+			continue
+		}
+		if policy != expected {
+			t.Errorf("parse %q: expected %+v but %+v got", input, expected, policy)
+		}
+	}
+
+	for _, input := range []string{"always:3", ":1", "on-failure:x", "on-failure:-1", "sometimes"} {
+		if _, err := ParseRestartPolicy(input); err == nil {
+			t.Errorf("parse %q: expected an error", input)
+		}
+	}
+}
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	onFailure := config.RestartPolicy{Name: config.RestartPolicyOnFailure, MaximumRetryCount: 2}
+	if onFailure.ShouldRestart(0, 0, false) {
+		t.Error("on-failure should not restart a container that exited with 0")
+	}
+	if !onFailure.ShouldRestart(1, 1, false) {
+		t.Error("on-failure should restart a failed container below the retry limit")
+	}
+	if onFailure.ShouldRestart(1, 2, false) {
+		t.Error("on-failure should stop restarting once the retry limit is reached")
+	}
+	always := config.RestartPolicy{Name: config.RestartPolicyAlways}
+	if !always.ShouldRestart(0, 100, false) {
+		t.Error("always should restart regardless of the exit code")
+	}
+	if always.ShouldRestart(137, 0, true) {
+		t.Error("a manually stopped container should not be restarted")
+	}
+}
@@ -0,0 +1,33 @@
+package streams
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/DeJeune/sudocker/cli/term"
+)
+
+// DefaultDetachKeys is the key sequence used to detach from a container when
+// neither the command line nor the configuration file overrides it.
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// ParseDetachKeys converts a comma-separated key sequence such as
+// "ctrl-p,ctrl-q" into the bytes the terminal sends for it. An empty
+// sequence selects DefaultDetachKeys.
+func ParseDetachKeys(keys string) ([]byte, error) {
+	if keys == "" {
+		keys = DefaultDetachKeys
+	}
+	escapeKeys, err := term.ToBytes(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid detach keys (%s) provided: %w", keys, err)
+	}
+	return escapeKeys, nil
+}
+
+// DetachProxy returns a reader over the input stream which stops with a
+// [term.EscapeError] once the detach key sequence is read. The keys of a
+// complete sequence are never passed on to the reader.
+func (i *In) DetachProxy(detachKeys []byte) io.Reader {
+	return term.NewEscapeProxy(i, detachKeys)
+}
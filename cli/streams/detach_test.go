@@ -0,0 +1,48 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/DeJeune/sudocker/cli/term"
+)
+
+func TestParseDetachKeys(t *testing.T) {
+	keys, err := ParseDetachKeys("")
+	if err != nil || string(keys) != "\x10\x11" {
+		t.Fatalf("expected default ctrl-p,ctrl-q, got %q, %v", keys, err)
+	}
+	keys, err = ParseDetachKeys("ctrl-a,x")
+	if err != nil || string(keys) != "\x01x" {
+		t.Fatalf("expected ctrl-a,x, got %q, %v", keys, err)
+	}
+	if _, err := ParseDetachKeys("ctrl-aa"); err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+}
+
+func TestDetachProxy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		detached bool
+	}{
+		{input: "echo hi\n", expected: "echo hi\n"},
+		{input: "ls\x10\x11rest", expected: "ls", detached: true},
+		// a partially matched sequence is passed through
+		{input: "a\x10b\x11", expected: "a\x10b\x11"},
+	}
+	for _, tc := range tests {
+		in := NewIn(io.NopCloser(strings.NewReader(tc.input)))
+		out, err := io.ReadAll(in.DetachProxy([]byte{0x10, 0x11}))
+		var escapeErr term.EscapeError
+		if detached := errors.As(err, &escapeErr); detached != tc.detached {
+			t.Errorf("%q: expected detached %v, got error %v", tc.input, tc.detached, err)
+		}
+		if string(out) != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.input, tc.expected, out)
+		}
+	}
+}
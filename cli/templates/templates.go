@@ -0,0 +1,92 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// basicFunctions 是模板中可以使用的辅助函数，和 docker --format 中的保持一致
+var basicFunctions = template.FuncMap{
+	"json": func(v any) string {
+		buf := &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(v)
+		// Remove the trailing new line added by the encoder
+		return strings.TrimSpace(buf.String())
+	},
+	"split":    strings.Split,
+	"join":     strings.Join,
+	"title":    strings.Title,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"pad":      padWithSpace,
+	"truncate": truncateWithLength,
+	"println":  fmt.Sprintln,
+}
+
+// HeaderFunctions 在渲染表头时替换basicFunctions，使表头中的列名保持原样
+var HeaderFunctions = template.FuncMap{
+	"json": func(v string) string {
+		return v
+	},
+	"split": func(v string, _ string) string {
+		// we want the table header to show the name of the column, and not
+		// split the table header itself. Using a string-slice here as the
+		// header doesn't make sense.
+		return v
+	},
+	"join": func(v string, _ string) string {
+		// table headers are always a string, so use a plain string here.
+		return v
+	},
+	"title": func(v string) string {
+		return v
+	},
+	"lower": func(v string) string {
+		return v
+	},
+	"upper": func(v string) string {
+		return v
+	},
+	"truncate": func(v string, _ int) string {
+		return v
+	},
+}
+
+// Parse 使用basicFunctions解析模板
+func Parse(format string) (*template.Template, error) {
+	return template.New("").Funcs(basicFunctions).Parse(format)
+}
+
+// New 创建一个带有basicFunctions的模板
+func New(tag string) *template.Template {
+	return template.New(tag).Funcs(basicFunctions)
+}
+
+// NewParse 使用给定的名称和basicFunctions解析模板
+func NewParse(tag, format string) (*template.Template, error) {
+	return New(tag).Parse(format)
+}
+
+// padWithSpace 在source前后分别补充prefix和suffix个空格
+func padWithSpace(source string, prefix, suffix int) string {
+	if source == "" {
+		return source
+	}
+	return strings.Repeat(" ", prefix) + source + strings.Repeat(" ", suffix)
+}
+
+// truncateWithLength 将source截断为最多length个字符
+func truncateWithLength(source string, length int) string {
+	if length < 0 {
+		return source
+	}
+	if len(source) < length {
+		return source
+	}
+	return source[:length]
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/DeJeune/sudocker/cmd"
 	"github.com/DeJeune/sudocker/cmd/container"
 	"github.com/DeJeune/sudocker/cmd/network"
+	"github.com/DeJeune/sudocker/cmd/system"
 	"github.com/spf13/cobra"
 )
 
@@ -11,13 +12,23 @@ func AddCommands(cmd *cobra.Command, sudockerCli *cmd.SudockerCli) {
 	cmd.AddCommand(
 		container.NewContainerCommand(sudockerCli),
 		container.NewInitCommand(sudockerCli),
+		container.NewMonitorCommand(sudockerCli),
 		container.NewRunCommand(sudockerCli),
+		container.NewAttachCommand(sudockerCli),
 		container.NewPsCommand(sudockerCli),
 		container.NewLogsCommand(sudockerCli),
 		container.NewCreateCommand(sudockerCli),
+		container.NewStartCommand(sudockerCli),
 		container.NewExecCommand(sudockerCli),
 		container.NewStopCommand(sudockerCli),
+		container.NewRestartCommand(sudockerCli),
+		container.NewPauseCommand(sudockerCli),
+		container.NewUnpauseCommand(sudockerCli),
+		container.NewWaitCommand(sudockerCli),
+		container.NewKillCommand(sudockerCli),
 		container.NewRmCommand(sudockerCli),
 		network.NewNetworkCommand(sudockerCli),
+		system.NewSystemCommand(sudockerCli),
+		system.NewInspectCommand(sudockerCli),
 	)
 }
@@ -6,11 +6,14 @@ package cmds
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/DeJeune/sudocker/cli"
 	cliflags "github.com/DeJeune/sudocker/cli/flag"
 	"github.com/DeJeune/sudocker/cli/version"
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/cmd/container"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -28,6 +31,9 @@ func NewSudockerCommand(sudockerCli *cmd.SudockerCli) *cli.TopLevelCommand {
 			}
 			return fmt.Errorf("sudocker: '%s' is not a sudocker command.\nSee 'sudocker --help'", args[0])
 		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			reconcileContainers(cmd)
+		},
 		TraverseChildren: true,
 		SilenceUsage:     true,
 		SilenceErrors:    true,
@@ -87,3 +93,22 @@ func hasTags(cmd *cobra.Command) bool {
 	}
 	return false
 }
+
+// skipReconcileCommands 是执行前不修正容器记录的命令：init、monitor 是在容器内或者由sudocker自身启动的，
+// system reconcile 自己修正并输出被修正的容器
+var skipReconcileCommands = map[string]bool{
+	"init":      true,
+	"monitor":   true,
+	"reconcile": true,
+}
+
+// reconcileContainers 在执行命令之前修正init进程已经不存在的容器记录，
+// 避免命令基于过期的状态操作容器，例如向被复用的pid发送信号
+func reconcileContainers(cmd *cobra.Command) {
+	if skipReconcileCommands[cmd.Name()] || os.Getenv(container.EnvExecPid) != "" {
+		return
+	}
+	if _, err := container.ReconcileContainers(); err != nil {
+		logrus.Warnf("reconcile containers: %v", err)
+	}
+}
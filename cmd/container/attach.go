@@ -0,0 +1,220 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cli/streams"
+	"github.com/DeJeune/sudocker/cli/term"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// AttachOptions group options for `attach` command
+type AttachOptions struct {
+	NoStdin    bool
+	Proxy      bool
+	DetachKeys string
+
+	Container string
+}
+
+func NewAttachCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	var opts AttachOptions
+
+	cmd := &cobra.Command{
+		Use:   "attach [OPTIONS] CONTAINER",
+		Short: "Attach local standard input, output, and error streams to a running container",
+		Args:  cli.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Container = args[0]
+			return RunAttach(cmd.Context(), sudockerCli, &opts)
+		},
+		Annotations: map[string]string{
+			"aliases": "sudocker container attach, sudocker attach",
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&opts.NoStdin, "no-stdin", false, "Do not attach STDIN")
+	flags.BoolVar(&opts.Proxy, "sig-proxy", true, "Proxy all received signals to the process")
+	flags.StringVar(&opts.DetachKeys, "detach-keys", "", "Override the key sequence for detaching a container")
+	return cmd
+}
+
+// RunAttach 通过容器monitor进程的控制socket重新连接到运行中容器的标准输入输出或者终端，
+// 直到容器退出或者读到detach的按键序列。容器退出时命令以容器的退出码退出
+func RunAttach(ctx context.Context, sudockerCli *cmd.SudockerCli, opts *AttachOptions) error {
+	info, err := container.GetInfoByContainerId(opts.Container)
+	if err != nil {
+		return errors.Errorf("Get container %s info error %v", opts.Container, err)
+	}
+	switch {
+	case info.Status == container.Paused:
+		return errors.New("You cannot attach to a paused container, unpause it first")
+	case info.Status != container.Running:
+		return errors.New("You cannot attach to a stopped container, start it first")
+	case !info.Monitored():
+		return errors.Errorf("container %s is not supervised by a monitor process and can't be attached", info.Id)
+	}
+
+	cfg := info.State.Config
+	attachStdin := !opts.NoStdin && cfg.OpenStdin
+	if err := sudockerCli.In().CheckTty(attachStdin, cfg.Tty); err != nil {
+		return err
+	}
+	detachKeys, err := parseDetachKeys(sudockerCli, opts.DetachKeys)
+	if err != nil {
+		return err
+	}
+
+	// 分配了终端时，Ctrl-C 等按键由容器的终端处理，不需要转发信号
+	if opts.Proxy && !cfg.Tty {
+		defer proxySignals(ctx, info.Id)()
+	}
+	exitCode, err := attachContainer(ctx, sudockerCli, info, attachedStreams{
+		tty:        cfg.Tty,
+		stdin:      attachStdin,
+		stdout:     sudockerCli.Out(),
+		stderr:     sudockerCli.Err(),
+		detachKeys: detachKeys,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return cli.StatusError{StatusCode: exitCode}
+	}
+	return nil
+}
+
+// attachContainer 连接到容器monitor进程的数据流，start不为nil时在连接之后启动容器，以免丢失容器最开始的输出。
+// 转发数据直到容器退出并返回退出码；用户detach时容器继续运行，返回0
+func attachContainer(ctx context.Context, sudockerCli cmd.Cli, info *container.Info, opts attachedStreams, start func() error) (int, error) {
+	stream, err := container.AttachMonitor(ctx, info.Id, opts.stdin)
+	if err != nil {
+		return -1, err
+	}
+	defer stream.Close()
+	since := time.Now().UTC()
+	if start != nil {
+		if err := start(); err != nil {
+			return -1, err
+		}
+	}
+	if opts.tty && sudockerCli.Out().IsTerminal() {
+		if err := MonitorTtySize(ctx, sudockerCli, info.Id, false); err != nil {
+			_, _ = fmt.Fprintln(sudockerCli.Err(), "Error monitoring TTY size:", err)
+		}
+	}
+	err = holdStreams(ctx, sudockerCli, stream, opts)
+	if isDetached(err) {
+		printDetached(sudockerCli, info)
+		return 0, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return container.WaitExitSince(ctx, info.Id, since)
+}
+
+// attachedStreams 描述attach时在本地与容器之间转发的数据流，stdout、stderr为nil时丢弃对应的输出
+type attachedStreams struct {
+	tty   bool
+	stdin bool
+	// detachKeys 是分配了终端时用来detach的按键序列
+	detachKeys []byte
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+// holdStreams 在本地的标准输入输出与attach的数据流之间转发数据，直到容器的输出结束。
+// 分配了终端时本地终端切换为raw模式，读到detach的按键序列时返回 term.EscapeError
+func holdStreams(ctx context.Context, sudockerCli cmd.Cli, stream *container.MonitorStream, opts attachedStreams) error {
+	if opts.tty && opts.stdin {
+		if err := sudockerCli.In().SetRawTerminal(); err != nil {
+			return err
+		}
+		defer sudockerCli.In().RestoreTerminal()
+		if err := sudockerCli.Out().SetRawTerminal(); err != nil {
+			return err
+		}
+		defer sudockerCli.Out().RestoreTerminal()
+	}
+	stdout, stderr := opts.stdout, opts.stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	outputDone := make(chan error, 1)
+	go func() {
+		var err error
+		if opts.tty {
+			_, err = io.Copy(stdout, stream)
+		} else {
+			_, err = stdcopy.StdCopy(stdout, stderr, stream)
+		}
+		outputDone <- err
+	}()
+
+	inputDone := make(chan error, 1)
+	if opts.stdin {
+		go func() {
+			var in io.Reader = sudockerCli.In()
+			if opts.tty {
+				in = sudockerCli.In().DetachProxy(opts.detachKeys)
+			}
+			_, err := io.Copy(stream, in)
+			// 本地的标准输入结束后只关闭写方向，继续接收容器的输出
+			_ = stream.CloseWrite()
+			inputDone <- err
+		}()
+	}
+
+	select {
+	case err := <-outputDone:
+		return err
+	case err := <-inputDone:
+		if isDetached(err) {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-outputDone:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseDetachKeys 解析detach的按键序列，依次使用命令行参数、配置文件中的 detachKeys 和默认的 ctrl-p,ctrl-q
+func parseDetachKeys(sudockerCli cmd.Cli, override string) ([]byte, error) {
+	keys := override
+	if keys == "" {
+		keys = sudockerCli.ConfigFile().DetachKeys
+	}
+	return streams.ParseDetachKeys(keys)
+}
+
+// isDetached 判断错误是否表示用户输入了detach的按键序列
+func isDetached(err error) bool {
+	var escapeErr term.EscapeError
+	return errors.As(err, &escapeErr)
+}
+
+// printDetached 提示用户已经从容器detach，容器继续在后台运行
+func printDetached(sudockerCli cmd.Cli, info *container.Info) {
+	_, _ = fmt.Fprintf(sudockerCli.Err(), "Detached from container %s, it keeps running. Use 'sudocker attach %s' to reattach.\n",
+		container.TruncateID(info.Id), info.Name)
+}
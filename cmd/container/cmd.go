@@ -15,6 +15,7 @@ func NewContainerCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 		},
 	}
 	cmd.AddCommand(
+		NewAttachCommand(sudockerCli),
 		NewCreateCommand(sudockerCli),
 		NewRunCommand(sudockerCli),
 		NewExecCommand(sudockerCli),
@@ -23,8 +24,12 @@ func NewContainerCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 		NewStartCommand(sudockerCli),
 		NewStopCommand(sudockerCli),
 		NewRestartCommand(sudockerCli),
+		NewPauseCommand(sudockerCli),
+		NewUnpauseCommand(sudockerCli),
+		NewWaitCommand(sudockerCli),
 		NewRmCommand(sudockerCli),
 		NewCommitCommand(sudockerCli),
+		NewInspectCommand(sudockerCli),
 		newListCommand(*sudockerCli),
 		NewLogsCommand(sudockerCli),
 		NewExecCommand(sudockerCli),
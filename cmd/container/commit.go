@@ -8,6 +8,9 @@ import (
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cli/opts"
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -55,11 +58,32 @@ func NewCommitCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 }
 
 func runCommit(ctx context.Context, sudockerCli cmd.Cli, options *commitOptions) error {
-	mntPath := "/root/merged"
-	imageTar := "/root/" + options.reference + ".tar"
-	fmt.Println("commitContainer imageTar:", imageTar)
+	info, err := container.GetInfoByContainerId(options.container)
+	if err != nil {
+		return errors.Errorf("Get container %s info error %v", options.container, err)
+	}
+	reference := options.reference
+	if reference == "" {
+		reference = info.Id
+	}
+	// 打包期间冻结容器，避免容器中的进程修改文件导致镜像内容不一致
+	if options.pause && info.Status == container.Running {
+		if err := container.PauseContainer(info.Id); err != nil {
+			return err
+		}
+		defer func() {
+			if err := container.UnpauseContainer(info.Id); err != nil {
+				logrus.Errorf("unpause container %s error %v", info.Id, err)
+			}
+		}()
+	}
+	// 将容器的rootfs打包为镜像，之后可以直接通过 sudocker run <reference> 使用
+	mntPath := utils.GetMerged(info.Id)
+	imageTar := utils.GetImage(reference)
+	logrus.Infof("commitContainer imageTar: %s", imageTar)
 	if _, err := exec.Command("tar", "-czf", imageTar, "-C", mntPath, ".").CombinedOutput(); err != nil {
-		logrus.Errorf("tar folder %s error %v", mntPath, err)
+		return errors.Errorf("tar folder %s error %v", mntPath, err)
 	}
+	_, _ = fmt.Fprintln(sudockerCli.Out(), reference)
 	return nil
 }
@@ -12,8 +12,6 @@ import (
 
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cmd"
-	"github.com/DeJeune/sudocker/runtime/config"
-	"github.com/DeJeune/sudocker/runtime/pkg/cgroups/fs"
 	"github.com/DeJeune/sudocker/runtime/pkg/container"
 	"github.com/DeJeune/sudocker/runtime/pkg/network"
 	"github.com/DeJeune/sudocker/runtime/utils"
@@ -29,6 +27,9 @@ const (
 	PullImageNever   = "never"
 )
 
+// defaultNetworkName 是没有指定 --net 时容器连接的网络
+const defaultNetworkName = "sudocker0"
+
 type createOptions struct {
 	name      string
 	platform  string
@@ -40,7 +41,41 @@ type createOptions struct {
 type ParentProcess struct {
 	cmd           *exec.Cmd
 	containerId   string
-	cgroupManager *fs.Manager
+	cgroupManager container.CgroupManager
+	// oomKillCount 是启动init进程时cgroup中已有的OOM kill次数，用来判断本次退出是否由OOM导致
+	oomKillCount uint64
+}
+
+// wait 等待容器的init进程退出，并将退出状态记录到容器信息中。
+// 容器已经被删除或者被重新启动时不修改记录，返回的容器信息为nil
+func (p *ParentProcess) wait() (container.ExitStatus, *container.Info, error) {
+	pid := p.cmd.Process.Pid
+	// 使用cmd.Wait而不是Process.Wait，保证前台运行时容器的输出被完整拷贝
+	if err := p.cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+			return container.ExitStatus{}, nil, errors.Errorf("wait container %s error %v", p.containerId, err)
+		}
+	}
+	status := container.NewExitStatus(p.cmd.ProcessState)
+	if p.cgroupManager != nil {
+		if count, err := p.cgroupManager.OOMKillCount(); err == nil && count > p.oomKillCount {
+			status.OOMKilled = true
+		}
+	}
+	skipped := false
+	info, err := container.UpdateContainerInfo(p.containerId, func(info *container.Info) error {
+		if info.Status != container.Stopped && info.Pid != strconv.Itoa(pid) {
+			skipped = true
+			return container.ErrSkipUpdate
+		}
+		info.SetExited(status)
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) || skipped {
+		return status, nil, nil
+	}
+	return status, info, err
 }
 
 func NewCreateCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
@@ -73,100 +108,211 @@ func runCreate(ctx context.Context, sudockerCli *cmd.SudockerCli, flags *pflag.F
 		reportError(sudockerCli.Err(), "create", err.Error(), true)
 		return cli.StatusError{StatusCode: 125}
 	}
-	parentProcess, err := newContainer(ctx, sudockerCli, containerConfig, options)
+	containerId, err := newContainer(ctx, sudockerCli, containerConfig, options)
 	if err != nil {
 		return err
 	}
-	_, _ = fmt.Fprintln(sudockerCli.Out(), parentProcess.containerId)
+	_, _ = fmt.Fprintln(sudockerCli.Out(), containerId)
 	return nil
 }
 
-func newContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, containerConfig *containerConfig, options *createOptions) (parentProcess *ParentProcess, err error) {
+// newContainer 创建一个新容器并返回容器ID。容器由后台的monitor进程创建init进程并看护，
+// 返回时容器处于Created状态，需要调用 container.StartContainer 启动
+func newContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, containerConfig *containerConfig, options *createOptions) (containerId string, err error) {
 	cg := containerConfig.Config
 	hostConfig := containerConfig.HostConfig
-	networkConfig := containerConfig.NetworkingConfig
 	// 模拟镜像拉取的过程
 	if err := pullImage(ctx, sudockerCli, cg.Image, options); err != nil {
+		return "", err
+	}
+	// 镜像中配置的环境变量可以被 -e 和 --env-file 覆盖
+	imageConfig, err := container.LoadImageConfig(cg.Image)
+	if err != nil {
+		return "", err
+	}
+	cg.Env = container.MergeEnv(imageConfig.Env, cg.Env)
+	containerId = container.GenerateContainerID()
+	// 没有指定名称时使用短ID作为容器名称
+	name := strings.TrimPrefix(options.name, "/")
+	if name == "" {
+		name = container.TruncateID(containerId)
+	}
+	if err := container.ReserveName(name, containerId); err != nil {
+		return "", err
+	}
+	// 出错时返回的containerId为空，因此在defer时传入ID。
+	// 已经写入的记录和rootfs需要一并删除，否则残留的记录仍然占用容器名称
+	recorded := false
+	defer func(id string) {
+		if err == nil {
+			return
+		}
+		if recorded {
+			if err := container.DeleteContainerInfo(id); err != nil {
+				logrus.Warnf("remove record of container %s: %v", id, err)
+			}
+			if err := container.DeleteStorageDriver(id, hostConfig.Binds); err != nil {
+				logrus.Warnf("remove rootfs of container %s: %v", id, err)
+			}
+		}
+		_ = container.ReleaseName(name, id)
+	}(containerId)
+	// 没有指定主机名时使用短ID作为主机名
+	if cg.Hostname == "" {
+		cg.Hostname = container.TruncateID(containerId)
+	}
+	created := time.Now()
+	info := &container.Info{
+		SchemaVersion: container.SchemaVersion,
+		Id:            containerId,
+		ImageName:     cg.Image,
+		Command:       strings.Join(cg.Cmd, " "),
+		Created:       created.Format("2006-01-02 15:04:05"),
+		Name:          name,
+		Volumes:       hostConfig.Binds,
+		PortMapping:   hostConfig.PortBindings,
+		State: container.BaseState{
+			ID:      containerId,
+			Created: created.UTC(),
+			Config:  *cg,
+		},
+		HostConfig:       hostConfig,
+		NetworkingConfig: containerConfig.NetworkingConfig,
+		Status:           container.Created,
+	}
+	// monitor进程记录容器的退出状态并负责重启，sudocker 命令退出后容器的退出状态不会丢失
+	if err := container.RecordContainerInfo(info); err != nil {
+		return "", err
+	}
+	recorded = true
+	if err := startMonitor(containerId); err != nil {
+		return "", err
+	}
+	return containerId, nil
+}
+
+// initContainer 在monitor进程中为容器启动init进程，init进程使用monitor进程持有的标准输入输出，
+// 并为其配置网络和cgroup。返回时init进程阻塞在exec.fifo上，容器处于Created状态。
+// 重新启动已停止的容器时同样经过这里，其overlay upper目录会被复用。
+// init进程启动之后出错时，结束init进程并释放已经分配的网络和cgroup
+func initContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, containerConfig *containerConfig, info *container.Info, stdio *containerStdio) (_ *ParentProcess, err error) {
+	cg := containerConfig.Config
+	hostConfig := containerConfig.HostConfig
+	networkConfig := containerConfig.NetworkingConfig
+	parentProcess := &ParentProcess{
+		containerId: info.Id,
+	}
+	parent, writePipe := container.NewParentProcess(ctx, cg, hostConfig, info.Id)
+	if parent == nil {
+		return nil, errors.Errorf("Failed to create parent process for container %s", info.Id)
+	}
+	// 提前在rootfs中解析用户，用户不存在时创建容器失败，而不是在启动时init进程退出
+	if _, err := container.ResolveUser(utils.GetMerged(info.Id), cg.User, hostConfig.GroupAdd); err != nil {
 		return nil, err
 	}
-	containerId := container.GenerateContainerID()
-	parentProcess = &ParentProcess{
-		containerId: containerId,
+	if err := container.SetupWorkdir(info.Id, cg.WorkingDir); err != nil {
+		return nil, err
 	}
-	parent, writePipe := container.NewParentProcess(ctx, sudockerCli, cg, hostConfig, containerId)
+	stdio.apply(parent)
 	parentProcess.cmd = parent
 	if err := parent.Start(); err != nil {
 		return nil, errors.Errorf("Failed to start parent process: %v", err)
 	}
-
-	info := &container.Info{
-		Pid:         strconv.Itoa(parent.Process.Pid),
-		Command:     strings.Join(cg.Cmd, ""),
-		Created:     time.Now().Format("2006-01-02 15:04:05"),
-		Id:          containerId,
-		Name:        map[bool]string{true: containerId, false: options.name}[options.name == ""],
-		Status:      container.Running,
-		Volumes:     containerConfig.HostConfig.Binds,
-		PortMapping: hostConfig.PortBindings,
+	stdio.started()
+	recorded := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		_ = parent.Process.Kill()
+		_ = parent.Wait()
+		releaseNetwork(info)
+		if parentProcess.cgroupManager != nil {
+			if err := parentProcess.cgroupManager.Destroy(); err != nil {
+				logrus.Warnf("destroy cgroup of container %s: %v", info.Id, err)
+			}
+		}
+		// 记录中的IP已经释放，避免之后再次释放被其他容器使用的IP
+		if recorded {
+			info.Pid = ""
+			info.State.InitProcessPid = 0
+			if err := container.RecordContainerInfo(info); err != nil {
+				logrus.Warnf("record container %s: %v", info.Id, err)
+			}
+		}
+	}()
+	info.Pid = strconv.Itoa(parent.Process.Pid)
+	info.State.InitProcessPid = parent.Process.Pid
+	// 记录init进程的启动时间，之后据此判断pid是否已经被其他进程复用
+	startTime, err := container.InitProcessStartTime(parent.Process.Pid)
+	if err != nil {
+		logrus.Warnf("read start time of init process %d: %v", parent.Process.Pid, err)
 	}
+	info.State.InitProcessStartTime = startTime
+	info.Status = container.Created
+	info.ExitCode = 0
+	// 重新启动已停止的容器时，先释放上一次运行分配的IP地址和端口映射
+	releaseNetwork(info)
+
 	// 如果没有指定网络，则分配默认网络sudocker0
 	net := networkConfig.Endpoints
 	logrus.Infof("portmap : %v", hostConfig.PortBindings)
 	if net == "" {
-		net = "sudocker0"
+		net = defaultNetworkName
 		res, err := network.ContainsNetwork(net)
 		if err != nil {
-			return parentProcess, err
+			return nil, err
 		}
 		if !res {
-			if err := network.CreateNetwork("bridge", "172.17.0.0/16", "sudocker0"); err != nil {
-				return parentProcess, err
+			if err := network.CreateNetwork("bridge", "172.17.0.0/16", defaultNetworkName); err != nil {
+				return nil, err
 			}
 		}
 
 	}
 	ip, err := network.Connect(net, info)
 	if err != nil {
-		return parentProcess, errors.Errorf("Error Connect Network %v", err)
+		return nil, errors.Errorf("Error Connect Network %v", err)
 	}
 	containerIP := ip.String()
 	info.IP = containerIP
+	if err := container.WriteHostFiles(info.Id, cg, containerIP); err != nil {
+		return nil, err
+	}
 
+	bootstrap, err := container.NewBootstrap(cg, hostConfig)
+	if err != nil {
+		return nil, err
+	}
+	info.State.Capabilities = bootstrap.Capabilities
 	if err := container.RecordContainerInfo(info); err != nil {
 		return nil, err
 	}
+	recorded = true
 
-	cgroupConfig := &config.Cgroup{
-		Name:      "sudocker-cgroup",
-		Rootless:  false,
-		Resources: hostConfig.Resources,
+	cgroupManager, err := container.NewCgroupManager(info.Id, hostConfig.Resources)
+	if err != nil {
+		return nil, err
 	}
-
-	cgroupManager, err := fs.NewManager(cgroupConfig, nil)
 	parentProcess.cgroupManager = cgroupManager
-	// defer cgroupManager.Destroy()
+	// 先创建cgroup并加入init进程，再设置资源限制，此时init进程还在等待引导消息
+	err = cgroupManager.Apply(parent.Process.Pid)
 	if err != nil {
 		return nil, err
 	}
-	err = cgroupManager.Set(cgroupConfig.Resources)
+	err = cgroupManager.Set(hostConfig.Resources)
 	if err != nil {
 		return nil, err
 	}
-	err = cgroupManager.Apply(parent.Process.Pid)
-	if err != nil {
+	if parentProcess.oomKillCount, err = cgroupManager.OOMKillCount(); err != nil {
+		logrus.Warnf("read oom kill count of container %s: %v", info.Id, err)
+	}
+	if err := bootstrap.Send(writePipe); err != nil {
 		return nil, err
 	}
-	sendInitCommand(cg.Cmd, writePipe)
 	return parentProcess, nil
 }
 
-// sendInitCommand 通过writePipe将指令发送给子进程
-func sendInitCommand(comArray []string, writePipe *os.File) {
-	command := strings.Join(comArray, " ")
-	_, _ = writePipe.WriteString(command)
-	_ = writePipe.Close()
-}
-
 func pullImage(ctx context.Context, sudockerCli *cmd.SudockerCli, img string, options *createOptions) error {
 	// 打开源文件
 	logrus.Infof("image name: %s", img)
@@ -2,44 +2,70 @@ package container
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"os/exec"
-	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	_ "github.com/DeJeune/sudocker/runtime/pkg/nsenter"
-	"github.com/DeJeune/sudocker/runtime/utils"
 
 	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cli/opts"
 	"github.com/DeJeune/sudocker/cmd"
 	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/capabilites"
 	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/pkg/user"
+	"github.com/DeJeune/sudocker/runtime/utils"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
 )
 
 const (
 	EnvExecPid = "mydocker_pid"
+	// EnvExecCmd 是执行的命令，每个参数编码为十六进制字符串，参数之间以逗号分隔，见 encodeArgs
 	EnvExecCmd = "mydocker_cmd"
+	// EnvExecUid、EnvExecGid、EnvExecGroups 是执行命令的用户，由nsenter在进入容器的namespace之后切换
+	EnvExecUid    = "mydocker_uid"
+	EnvExecGid    = "mydocker_gid"
+	EnvExecGroups = "mydocker_groups"
+	// EnvExecCwd 是执行命令的工作目录，由nsenter在切换用户之前进入
+	EnvExecCwd = "mydocker_cwd"
+	// EnvExecCaps 是执行命令的capabilities的十六进制位掩码
+	EnvExecCaps = "mydocker_caps"
+	// EnvExecSeccomp 是执行命令的seccomp过滤器，每条BPF指令编码为16个十六进制字符
+	EnvExecSeccomp = "mydocker_seccomp"
+	// EnvExecNoNewPrivs 存在时nsenter设置 PR_SET_NO_NEW_PRIVS，并在执行命令之前才加载seccomp过滤器
+	EnvExecNoNewPrivs = "mydocker_no_new_privs"
 )
 
+// ExecOptions 是 exec 的命令行参数。命令直接使用 sudocker 的标准输入输出执行，不分配伪终端，
+// 因此 exec 没有 --detach-keys，需要在后台执行的命令使用 -d
 type ExecOptions struct {
-	DetachKeys  string
 	Interactive bool
 	TTY         bool
 	Detach      bool
 	User        string
+	GroupAdd    []string
+	CapAdd      []string
+	CapDrop     []string
+	Env         opts.ListOpts
 	Privileged  bool
+	SecurityOpt opts.ListOpts
 	Workdir     string
 	Command     []string
 }
 
 func NewExecCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
-	var options ExecOptions
+	options := ExecOptions{
+		Env:         opts.NewListOpts(opts.ValidateEnv),
+		SecurityOpt: opts.NewListOpts(nil),
+	}
 
 	cmd := &cobra.Command{
 		Use:   "exec [OPTIONS] CONTAINER COMMAND [ARG...]",
@@ -65,25 +91,65 @@ func NewExecCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	flags := cmd.Flags()
 	flags.SetInterspersed(false)
 
-	flags.StringVarP(&options.DetachKeys, "detach-keys", "", "", "Override the key sequence for detaching a container")
 	flags.BoolVarP(&options.Interactive, "interactive", "i", false, "Keep STDIN open even if not attached")
 	flags.BoolVarP(&options.TTY, "tty", "t", false, "Allocate a pseudo-TTY")
 	flags.BoolVarP(&options.Detach, "detach", "d", false, "Detached mode: run command in the background")
 	flags.StringVarP(&options.User, "user", "u", "", "Username or UID (format: <name|uid>[:<group|gid>])")
+	flags.StringSliceVar(&options.GroupAdd, "group-add", nil, "Add additional groups to join")
+	flags.StringSliceVar(&options.CapAdd, "cap-add", nil, "Add Linux capabilities")
+	flags.StringSliceVar(&options.CapDrop, "cap-drop", nil, "Drop Linux capabilities")
+	flags.StringVarP(&options.Workdir, "workdir", "w", "", "Working directory inside the container")
+	flags.VarP(&options.Env, "env", "e", "Set environment variables")
 	flags.BoolVarP(&options.Privileged, "privileged", "", false, "Give extended privileges to the command")
+	flags.Var(&options.SecurityOpt, "security-opt", "Security Options")
 
 	return cmd
 }
 
 func RunExec(ctx context.Context, sudockerCli *cmd.SudockerCli, containerIDorName string, options ExecOptions) error {
-	pid, err := getPidByContainerId(containerIDorName)
+	info, err := container.GetInfoByContainerId(containerIDorName)
 	if err != nil {
 		return errors.Errorf("Exec container getContainerPidByName %s error %v", containerIDorName, err)
 	}
+	pid := info.Pid
 	execOptions, err := parseExec(options)
 	if err != nil {
 		return err
 	}
+	execUser, err := resolveExecUser(info, execOptions)
+	if err != nil {
+		return err
+	}
+	// 没有指定工作目录时使用容器的工作目录
+	workdir := execOptions.WorkingDir
+	if workdir == "" && info.HasFullConfig() {
+		workdir = info.State.Config.WorkingDir
+	}
+	if workdir != "" && !filepath.IsAbs(workdir) {
+		return errors.Errorf("the working directory '%s' is invalid, it needs to be an absolute path", workdir)
+	}
+	if err := container.SetupWorkdir(info.Id, workdir); err != nil {
+		return err
+	}
+	caps, err := execCapabilities(info, execOptions)
+	if err != nil {
+		return err
+	}
+	// exec的 --security-opt 覆盖容器的同名选项。特权容器中的命令和 --privileged 的命令不过滤系统调用
+	privileged := execOptions.Privileged
+	var securityOpts []string
+	if info.HasFullConfig() {
+		privileged = privileged || info.HostConfig.Privileged
+		securityOpts = append(securityOpts, info.HostConfig.SecurityOpt...)
+	}
+	security, err := container.ParseSecurityOpts(append(securityOpts, execOptions.SecurityOpt...))
+	if err != nil {
+		return err
+	}
+	filter, err := security.SeccompFilter(privileged, caps)
+	if err != nil {
+		return err
+	}
 
 	if !execOptions.Detach {
 		if err := sudockerCli.In().CheckTty(execOptions.AttachStdin, execOptions.Tty); err != nil {
@@ -109,18 +175,47 @@ func RunExec(ctx context.Context, sudockerCli *cmd.SudockerCli, containerIDorNam
 		}
 	}
 
-	// 把命令拼接成字符串，便于传递
-	cmdStr := strings.Join(execOptions.Cmd, " ")
-	logrus.Infof("container pid: %s command: %s", pid, cmdStr)
-	_ = os.Setenv(EnvExecPid, pid)
-	_ = os.Setenv(EnvExecCmd, cmdStr)
+	logrus.Infof("container pid: %s command: %s", pid, strings.Join(execOptions.Cmd, " "))
 	containerEnvs, err := getEnvsByPid(pid)
 	if err != nil {
 		return errors.Errorf("get env value failed: %v", err)
 	}
-	cmd.Env = append(os.Environ(), containerEnvs...)
+	// 指定了用户时使用该用户的HOME，否则沿用容器的HOME
+	home := execUser.Home
+	if value, ok := lookupEnv(containerEnvs, "HOME"); ok && options.User == "" {
+		home = value
+	}
+	var defaultEnv []string
+	if execOptions.Tty {
+		defaultEnv = append(defaultEnv, "TERM=xterm")
+	}
+	// 命令的环境变量来自容器的init进程和 -e，不继承宿主机的环境变量。
+	// nsenter 使用的变量在执行命令之前被删除
+	cmd.Env = container.MergeEnv(defaultEnv, containerEnvs, []string{"HOME=" + home}, execOptions.Env)
+	cmd.Env = append(cmd.Env,
+		EnvExecPid+"="+pid,
+		EnvExecCmd+"="+encodeArgs(execOptions.Cmd),
+		EnvExecUid+"="+strconv.Itoa(execUser.Uid),
+		EnvExecGid+"="+strconv.Itoa(execUser.Gid),
+		EnvExecGroups+"="+joinInts(execUser.Sgids),
+		EnvExecCaps+"="+strconv.FormatUint(capabilites.Mask(caps), 16),
+	)
+	if workdir != "" {
+		cmd.Env = append(cmd.Env, EnvExecCwd+"="+workdir)
+	}
+	if len(filter) > 0 {
+		cmd.Env = append(cmd.Env, EnvExecSeccomp+"="+encodeFilter(filter))
+	}
+	if security.NoNewPrivileges {
+		cmd.Env = append(cmd.Env, EnvExecNoNewPrivs+"=1")
+	}
 
 	if err = cmd.Run(); err != nil {
+		// 命令以非0的退出码退出时，sudocker exec 以相同的退出码退出
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			return cli.StatusError{StatusCode: exitErr.ExitCode()}
+		}
 		return errors.Errorf("Exec container %s error %v", containerIDorName, err)
 	}
 
@@ -133,13 +228,22 @@ func RunExec(ctx context.Context, sudockerCli *cmd.SudockerCli, containerIDorNam
 }
 
 func parseExec(execOpts ExecOptions) (*config.ExecOptions, error) {
+	securityOpts, err := parseSecurityOpts(execOpts.SecurityOpt.GetAll())
+	if err != nil {
+		return nil, err
+	}
 	execOptions := &config.ExecOptions{
-		User:       execOpts.User,
-		Privileged: execOpts.Privileged,
-		Tty:        execOpts.TTY,
-		Cmd:        execOpts.Command,
-		Detach:     execOpts.Detach,
-		WorkingDir: execOpts.Workdir,
+		User:        execOpts.User,
+		GroupAdd:    execOpts.GroupAdd,
+		CapAdd:      execOpts.CapAdd,
+		CapDrop:     execOpts.CapDrop,
+		Env:         execOpts.Env.GetAll(),
+		Privileged:  execOpts.Privileged,
+		SecurityOpt: securityOpts,
+		Tty:         execOpts.TTY,
+		Cmd:         execOpts.Command,
+		Detach:      execOpts.Detach,
+		WorkingDir:  execOpts.Workdir,
 	}
 
 	// If -d is not set, attach to everything by default
@@ -150,10 +254,6 @@ func parseExec(execOpts ExecOptions) (*config.ExecOptions, error) {
 			execOptions.AttachStdin = true
 		}
 	}
-
-	if execOpts.DetachKeys != "" {
-		execOptions.DetachKeys = execOpts.DetachKeys
-	}
 	return execOptions, nil
 }
 
@@ -164,20 +264,19 @@ func fillConsoleSize(execOptions *config.ExecOptions, sudockerCli cmd.Cli) {
 	}
 }
 
-func getPidByContainerId(containerId string) (string, error) {
-	// 拼接出记录容器信息的文件路径
-	dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
-	configFilePath := path.Join(dirPath, utils.ConfigName)
-	// 读取内容并解析
-	contentBytes, err := os.ReadFile(configFilePath)
-	if err != nil {
-		return "", err
-	}
-	var containerInfo container.Info
-	if err = json.Unmarshal(contentBytes, &containerInfo); err != nil {
-		return "", err
+// resolveExecUser 在容器的rootfs中解析执行命令的用户，没有指定时使用容器的用户。
+// 容器的 --group-add 同样对exec的命令生效
+func resolveExecUser(info *container.Info, execOptions *config.ExecOptions) (*user.ExecUser, error) {
+	spec := execOptions.User
+	var groupAdd []string
+	if info.HasFullConfig() {
+		if spec == "" {
+			spec = info.State.Config.User
+		}
+		groupAdd = append(groupAdd, info.HostConfig.GroupAdd...)
 	}
-	return containerInfo.Pid, nil
+	groupAdd = append(groupAdd, execOptions.GroupAdd...)
+	return container.ResolveUser(utils.GetMerged(info.Id), spec, groupAdd)
 }
 
 // getEnvsByPid 读取指定PID进程的环境变量
@@ -191,3 +290,52 @@ func getEnvsByPid(pid string) ([]string, error) {
 	envs := strings.Split(string(contentBytes), "\u0000")
 	return envs, nil
 }
+
+// execCapabilities 在容器进程的capabilities的基础上按照exec的 --cap-add 和 --cap-drop 计算命令的capabilities，
+// --privileged 的命令拥有所有的capabilities
+func execCapabilities(info *container.Info, execOptions *config.ExecOptions) ([]string, error) {
+	if execOptions.Privileged {
+		return capabilites.AllCapabilities(), nil
+	}
+	base := capabilites.DefaultCapabilities()
+	if info.State.Capabilities != nil {
+		base = info.State.Capabilities.Bounding
+	}
+	return capabilites.TweakCapabilities(base, execOptions.CapAdd, execOptions.CapDrop)
+}
+
+// lookupEnv 在 KEY=VALUE 形式的环境变量中查找key，有多个时以最后一个为准
+func lookupEnv(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(env[i], "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// encodeArgs 将命令编码为nsenter读取的字符串，参数中可以包含任意字符
+func encodeArgs(args []string) string {
+	encoded := make([]string, 0, len(args))
+	for _, arg := range args {
+		encoded = append(encoded, hex.EncodeToString([]byte(arg)))
+	}
+	return strings.Join(encoded, ",")
+}
+
+// encodeFilter 将BPF程序编码为nsenter读取的十六进制字符串
+func encodeFilter(filter []unix.SockFilter) string {
+	var b strings.Builder
+	for _, insn := range filter {
+		fmt.Fprintf(&b, "%04x%02x%02x%08x", insn.Code, insn.Jt, insn.Jf, insn.K)
+	}
+	return b.String()
+}
+
+func joinInts(ints []int) string {
+	strs := make([]string, 0, len(ints))
+	for _, i := range ints {
+		strs = append(strs, strconv.Itoa(i))
+	}
+	return strings.Join(strs, ",")
+}
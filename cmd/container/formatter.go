@@ -0,0 +1,316 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/DeJeune/sudocker/cli/templates"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+)
+
+const (
+	tableFormatKey = "table"
+	jsonFormatKey  = "json"
+
+	defaultContainerTableFormat = "table {{.ID}}\t{{.Image}}\t{{.Command}}\t{{.RunningFor}}\t{{.Status}}\t{{.Ports}}\t{{.Names}}"
+	quietContainerFormat        = "{{.ID}}"
+
+	commandTruncLength  = 20
+	createdTimeLayout   = "2006-01-02 15:04:05"
+	createdAtOutputForm = "2006-01-02 15:04:05 -0700 MST"
+)
+
+// containerHeaders 是表格输出时每一列的表头
+var containerHeaders = headerContext{
+	"ID":           "CONTAINER ID",
+	"Names":        "NAMES",
+	"Image":        "IMAGE",
+	"Command":      "COMMAND",
+	"CreatedAt":    "CREATED AT",
+	"RunningFor":   "CREATED",
+	"Ports":        "PORTS",
+	"State":        "STATE",
+	"Status":       "STATUS",
+	"Size":         "SIZE",
+	"Labels":       "LABELS",
+	"Networks":     "NETWORKS",
+	"Mounts":       "MOUNTS",
+	"Pid":          "PID",
+	"RestartCount": "RESTARTS",
+}
+
+// headerContext 是渲染表头时的模板上下文，{{.Label "key"}} 这类方法调用同样输出列名
+type headerContext map[string]string
+
+func (h headerContext) Label(name string) string {
+	return "LABEL"
+}
+
+// newContainerFormat 根据 --format、--quiet 和 --size 确定 ps 使用的模板
+func newContainerFormat(source string, quiet, size bool) string {
+	switch {
+	case quiet:
+		return quietContainerFormat
+	case source == "" || source == tableFormatKey:
+		if size {
+			return defaultContainerTableFormat + "\t{{.Size}}"
+		}
+		return defaultContainerTableFormat
+	case source == jsonFormatKey:
+		return "{{json .}}"
+	}
+	return source
+}
+
+// writeContainers 使用format渲染容器列表，format以 table 开头时输出带表头的对齐表格
+func writeContainers(out io.Writer, format string, containers []*container.Info, trunc, size bool) error {
+	isTable := strings.HasPrefix(format, tableFormatKey)
+	if isTable {
+		format = strings.TrimLeft(format[len(tableFormatKey):], " ")
+	}
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	tmpl, err := templates.Parse(format)
+	if err != nil {
+		return errors.Errorf("template parsing error: %v", err)
+	}
+
+	w := out
+	var tw *tabwriter.Writer
+	if isTable {
+		tw = tabwriter.NewWriter(out, 10, 1, 3, ' ', 0)
+		w = tw
+		if err := writeHeader(tw, format); err != nil {
+			return err
+		}
+	}
+	for _, info := range containers {
+		if err := tmpl.Execute(w, &containerContext{info: info, trunc: trunc, size: size}); err != nil {
+			return errors.Errorf("template parsing error: %v", err)
+		}
+		_, _ = io.WriteString(w, "\n")
+	}
+	if tw != nil {
+		return tw.Flush()
+	}
+	return nil
+}
+
+// writeHeader 使用 HeaderFunctions 渲染表头，使 {{upper .Names}} 这类列也输出原始列名
+func writeHeader(w io.Writer, format string) error {
+	tmpl, err := template.New("").Funcs(templates.HeaderFunctions).Parse(format)
+	if err != nil {
+		return errors.Errorf("template parsing error: %v", err)
+	}
+	if err := tmpl.Execute(w, containerHeaders); err != nil {
+		return errors.Errorf("template parsing error: %v", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// containerContext 是 ps 渲染单个容器时的模板上下文
+type containerContext struct {
+	info  *container.Info
+	trunc bool
+	size  bool
+}
+
+// MarshalJSON 在 --format json 时输出所有列，只有指定了 --size 时才统计大小
+func (c *containerContext) MarshalJSON() ([]byte, error) {
+	size := ""
+	if c.size {
+		size = c.Size()
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(map[string]any{
+		"ID":           c.ID(),
+		"Names":        c.Names(),
+		"Image":        c.Image(),
+		"Command":      c.Command(),
+		"CreatedAt":    c.CreatedAt(),
+		"RunningFor":   c.RunningFor(),
+		"Ports":        c.Ports(),
+		"State":        c.State(),
+		"Status":       c.Status(),
+		"Size":         size,
+		"Labels":       c.Labels(),
+		"Networks":     c.Networks(),
+		"Mounts":       c.Mounts(),
+		"Pid":          c.Pid(),
+		"RestartCount": c.RestartCount(),
+	})
+	return bytes.TrimSpace(buf.Bytes()), err
+}
+
+func (c *containerContext) ID() string {
+	if c.trunc {
+		return container.TruncateID(c.info.Id)
+	}
+	return c.info.Id
+}
+
+func (c *containerContext) Names() string {
+	return c.info.Name
+}
+
+func (c *containerContext) Image() string {
+	return c.info.ImageName
+}
+
+func (c *containerContext) Command() string {
+	command := c.info.Command
+	if c.trunc && len(command) > commandTruncLength {
+		command = command[:commandTruncLength-1] + "…"
+	}
+	return strconv.Quote(command)
+}
+
+func (c *containerContext) CreatedAt() string {
+	return createdTime(c.info).Local().Format(createdAtOutputForm)
+}
+
+func (c *containerContext) RunningFor() string {
+	return units.HumanDuration(time.Since(createdTime(c.info))) + " ago"
+}
+
+// Ports 按照 0.0.0.0:8080->80/tcp 的格式输出端口映射
+func (c *containerContext) Ports() string {
+	ports := make([]string, 0, len(c.info.PortMapping))
+	for _, pm := range c.info.PortMapping {
+		hostPort, containerPort, ok := strings.Cut(pm, ":")
+		if !ok {
+			continue
+		}
+		if !strings.Contains(containerPort, "/") {
+			containerPort += "/tcp"
+		}
+		ports = append(ports, fmt.Sprintf("0.0.0.0:%s->%s", hostPort, containerPort))
+	}
+	return strings.Join(ports, ", ")
+}
+
+func (c *containerContext) State() string {
+	return containerState(c.info)
+}
+
+// Status 输出容器状态的可读描述，如 Up 5 minutes、Exited (0) 2 hours ago
+func (c *containerContext) Status() string {
+	info := c.info
+	switch info.Status {
+	case container.Created:
+		return "Created"
+	case container.Running, container.Paused:
+		status := "Up"
+		if !info.StartedAt.IsZero() {
+			status += " " + units.HumanDuration(time.Since(info.StartedAt))
+		}
+		if info.Status == container.Paused {
+			status += " (Paused)"
+		}
+		return status
+	case container.Stopped:
+		status := fmt.Sprintf("Exited (%d)", info.ExitCode)
+		if !info.FinishedAt.IsZero() {
+			status += " " + units.HumanDuration(time.Since(info.FinishedAt)) + " ago"
+		}
+		return status
+	}
+	return info.Status.String()
+}
+
+// Size 输出容器可写层的大小，以及加上镜像层后的虚拟大小
+func (c *containerContext) Size() string {
+	rw := dirSize(utils.GetUpper(c.info.Id))
+	virtual := rw + dirSize(utils.GetLower(c.info.Id))
+	return fmt.Sprintf("%s (virtual %s)", units.HumanSizeWithPrecision(float64(rw), 3), units.HumanSizeWithPrecision(float64(virtual), 3))
+}
+
+func (c *containerContext) Labels() string {
+	labels := c.info.State.Config.Labels
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (c *containerContext) Label(name string) string {
+	return c.info.State.Config.Labels[name]
+}
+
+func (c *containerContext) Networks() string {
+	return containerNetwork(c.info)
+}
+
+func (c *containerContext) Mounts() string {
+	return strings.Join(c.info.Volumes, ",")
+}
+
+func (c *containerContext) Pid() string {
+	return c.info.Pid
+}
+
+func (c *containerContext) RestartCount() int {
+	return c.info.RestartCount
+}
+
+// containerState 返回容器状态在 ps 和 --filter status= 中使用的名称，已停止的容器为 exited
+func containerState(info *container.Info) string {
+	if info.Status == container.Stopped {
+		return "exited"
+	}
+	return info.Status.String()
+}
+
+// containerNetwork 返回容器连接的网络，没有指定网络的容器连接的是默认网络
+func containerNetwork(info *container.Info) string {
+	if info.NetworkingConfig == nil || info.NetworkingConfig.Endpoints == "" {
+		return defaultNetworkName
+	}
+	return info.NetworkingConfig.Endpoints
+}
+
+// createdTime 返回容器的创建时间，旧的记录中只有本地时间格式的字符串
+func createdTime(info *container.Info) time.Time {
+	if !info.State.Created.IsZero() {
+		return info.State.Created
+	}
+	created, err := time.ParseInLocation(createdTimeLayout, info.Created, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// dirSize 统计目录下所有普通文件的大小，目录不存在时返回0
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,57 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/cmd/inspect"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type inspectOptions struct {
+	format string
+	refs   []string
+}
+
+func NewInspectCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	var opts inspectOptions
+
+	cmd := &cobra.Command{
+		Use:   "inspect [OPTIONS] CONTAINER [CONTAINER...]",
+		Short: "Display detailed information on one or more containers",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.refs = args
+			return runInspect(cmd.Context(), sudockerCli, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.format, "format", "f", "", "Format output using a custom template:\n'json':             Print in JSON format\n'TEMPLATE':         Print output using the given Go template.")
+	return cmd
+}
+
+func runInspect(ctx context.Context, sudockerCli cmd.Cli, opts inspectOptions) error {
+	return inspect.Inspect(sudockerCli.Out(), opts.refs, opts.format, GetContainerRef)
+}
+
+// GetContainerRef 是 inspect 获取容器信息的 GetRefFunc
+func GetContainerRef(ref string) (any, []byte, error) {
+	info, err := container.GetInfoByContainerId(ref)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, inspect.NotFound("container", ref)
+		}
+		return nil, nil, err
+	}
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, raw, nil
+}
@@ -1,13 +1,21 @@
 package container
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/moby/sys/signal"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 type killOptions struct {
 	signal string
+	all    bool
 
 	containers []string
 }
@@ -20,11 +28,39 @@ func NewKillCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 		Short: "Kill one or more running containers",
 		Args:  cli.RequiresMinArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return nil
+			opts.containers = args
+			return runKill(cmd.Context(), sudockerCli, &opts)
+		},
+		Annotations: map[string]string{
+			"aliases": "sudocker container kill, sudocker kill",
 		},
 	}
 
 	flags := cmd.Flags()
 	flags.StringVarP(&opts.signal, "signal", "s", "KILL", "Signal to send to the container")
+	flags.BoolVar(&opts.all, "all", false, "Send the signal to all processes in the container's cgroup")
 	return cmd
 }
+
+func runKill(ctx context.Context, sudockerCli cmd.Cli, opts *killOptions) error {
+	// 支持 SIGHUP、HUP、hup 以及数字形式的信号
+	sig, err := signal.ParseSignal(opts.signal)
+	if err != nil {
+		return err
+	}
+	errChan := parallelOperation(ctx, opts.containers, func(ctx context.Context, containerId string) error {
+		return releaseStale(container.KillContainer(containerId, sig, opts.all))
+	})
+	var errs []string
+	for _, name := range opts.containers {
+		if err := <-errChan; err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		_, _ = fmt.Fprintln(sudockerCli.Out(), name)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -2,16 +2,15 @@ package container
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"os"
-	"path"
-	"text/tabwriter"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cli/opts"
 	"github.com/DeJeune/sudocker/cmd"
 	"github.com/DeJeune/sudocker/runtime/pkg/container"
-	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -25,10 +24,36 @@ type psOptions struct {
 	nLatest bool
 	last    int
 	format  string
+	filter  opts.FilterOpt
+}
+
+// acceptedPsFilterTags 是 ps --filter 支持的过滤条件
+var acceptedPsFilterTags = map[string]bool{
+	"status":   true,
+	"name":     true,
+	"id":       true,
+	"label":    true,
+	"network":  true,
+	"ancestor": true,
+	"before":   true,
+	"since":    true,
+	"exited":   true,
+}
+
+// acceptedStatusFilters 是 --filter status= 可以使用的状态，stopped 等同于 exited
+var acceptedStatusFilters = map[string]bool{
+	"created":    true,
+	"restarting": true,
+	"running":    true,
+	"removing":   true,
+	"paused":     true,
+	"exited":     true,
+	"dead":       true,
+	"stopped":    true,
 }
 
 func NewPsCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
-	options := psOptions{}
+	options := psOptions{filter: opts.NewFilterOpt()}
 
 	cmd := &cobra.Command{
 		Use:   "ps [OPTIONS]",
@@ -45,14 +70,14 @@ func NewPsCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 
 	flags := cmd.Flags()
 
-	flags.BoolVarP(&options.quiet, "quiet", "q", false, "Only display numeric IDs")
+	flags.BoolVarP(&options.quiet, "quiet", "q", false, "Only display container IDs")
 	flags.BoolVarP(&options.size, "size", "s", false, "Display total file sizes")
 	flags.BoolVarP(&options.all, "all", "a", false, "Show all containers (default shows just running)")
 	flags.BoolVar(&options.noTrunc, "no-trunc", false, "Don't truncate output")
 	flags.BoolVarP(&options.nLatest, "latest", "l", false, "Show the latest created container (includes all states)")
 	flags.IntVarP(&options.last, "last", "n", -1, "Show n last created containers (includes all states)")
 	flags.StringVarP(&options.format, "format", "", "", "Pretty-print containers using a Go template")
-	// flags.VarP(&options.filter, "filter", "f", "Filter output based on conditions provided")
+	flags.VarP(&options.filter, "filter", "f", "Filter output based on conditions provided")
 
 	return cmd
 }
@@ -65,60 +90,135 @@ func newListCommand(sudockerCli cmd.SudockerCli) *cobra.Command {
 }
 
 func runPs(ctx context.Context, sudockerCli cmd.Cli, options *psOptions) error {
-	// 读取存放容器信息目录下的所有文件
-	files, err := os.ReadDir(utils.InfoLoc)
+	containers, err := listContainers(options)
 	if err != nil {
-		return errors.Errorf("read dir %s error %v", utils.InfoLoc, err)
+		return err
 	}
-	containers := make([]*container.Info, 0, len(files))
-	for _, file := range files {
-		tmpContainer, err := getContainerInfo(file)
-		if err != nil {
-			logrus.Errorf("get container info error %v", err)
-			continue
-		}
-		containers = append(containers, tmpContainer)
+	format := options.format
+	if format == "" {
+		format = sudockerCli.ConfigFile().PsFormat
 	}
-	// 使用tabwriter.NewWriter在控制台打印出容器信息
-	// tabwriter 是引用的text/tabwriter类库，用于在控制台打印对齐的表格
-	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
-	_, err = fmt.Fprint(w, "ID\tNAME\tPID\tSTATUS\tCOMMAND\tCREATED\n")
+	return writeContainers(sudockerCli.Out(), newContainerFormat(format, options.quiet, options.size), containers, !options.noTrunc, options.size)
+}
+
+// listContainers 读取所有容器的信息，按照创建时间从新到旧排序，并根据选项过滤
+func listContainers(options *psOptions) ([]*container.Info, error) {
+	containers, err := container.ListContainerInfos(func(containerId string, err error) {
+		logrus.Errorf("get container %s info error %v", containerId, err)
+	})
 	if err != nil {
-		return errors.Errorf("Fprint error %v", err)
+		return nil, err
 	}
-	for _, item := range containers {
-		_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
-			item.Id,
-			item.Name,
-			item.Pid,
-			item.Status,
-			item.Command,
-			item.Created)
-		if err != nil {
-			logrus.Errorf("Fprint error %v", err)
+	sort.SliceStable(containers, func(i, j int) bool {
+		return createdTime(containers[i]).After(createdTime(containers[j]))
+	})
+	return filterContainers(containers, options)
+}
+
+// filterContainers 按照 --filter、--all、--last、--latest 过滤已经按创建时间排好序的容器
+func filterContainers(containers []*container.Info, options *psOptions) ([]*container.Info, error) {
+	psFilters := options.filter.Value()
+	if err := psFilters.Validate(acceptedPsFilterTags); err != nil {
+		return nil, err
+	}
+	for _, status := range psFilters.Get("status") {
+		if !acceptedStatusFilters[status] {
+			return nil, errors.Errorf("invalid filter 'status=%s'", status)
 		}
 	}
-	if err = w.Flush(); err != nil {
-		return errors.Errorf("Flush error %v", err)
+	exitCodes := make(map[int]bool)
+	for _, value := range psFilters.Get("exited") {
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.Errorf("invalid filter 'exited=%s'", value)
+		}
+		exitCodes[code] = true
 	}
-	return nil
-}
-
-func getContainerInfo(file os.DirEntry) (*container.Info, error) {
-	// 根据文件名拼接出完整路径
-	configFileDir := fmt.Sprintf(utils.InfoLocFormat, file.Name())
-	configFileDir = path.Join(configFileDir, utils.ConfigName)
-	// 读取容器配置文件
-	content, err := os.ReadFile(configFileDir)
+	before, err := referenceCreatedTime(containers, psFilters, "before")
 	if err != nil {
-		logrus.Errorf("read file %s error %v", configFileDir, err)
 		return nil, err
 	}
-	info := new(container.Info)
-	if err = json.Unmarshal(content, info); err != nil {
-		logrus.Errorf("json unmarshal error %v", err)
+	since, err := referenceCreatedTime(containers, psFilters, "since")
+	if err != nil {
 		return nil, err
 	}
 
-	return info, nil
+	last := options.last
+	if options.nLatest {
+		last = 1
+	}
+	// 默认只显示运行中的容器，指定了状态相关的过滤条件时同样包含已停止的容器
+	all := options.all || last > 0 || psFilters.Contains("status") || psFilters.Contains("exited")
+
+	filtered := make([]*container.Info, 0, len(containers))
+	for _, info := range containers {
+		if last > 0 && len(filtered) == last {
+			break
+		}
+		if !all && info.Status != container.Running && info.Status != container.Paused {
+			continue
+		}
+		if psFilters.Contains("status") && !psFilters.ExactMatch("status", containerState(info)) &&
+			!(info.Status == container.Stopped && psFilters.ExactMatch("status", "stopped")) {
+			continue
+		}
+		if len(exitCodes) > 0 && (info.Status != container.Stopped || !exitCodes[info.ExitCode]) {
+			continue
+		}
+		if !psFilters.Match("name", info.Name) || !psFilters.Match("id", info.Id) {
+			continue
+		}
+		if !psFilters.MatchKVList("label", info.State.Config.Labels) {
+			continue
+		}
+		if psFilters.Contains("network") && !psFilters.ExactMatch("network", containerNetwork(info)) {
+			continue
+		}
+		if psFilters.Contains("ancestor") && !psFilters.ExactMatch("ancestor", info.ImageName) {
+			continue
+		}
+		created := createdTime(info)
+		if !before.IsZero() && !created.Before(before) {
+			continue
+		}
+		if !since.IsZero() && !created.After(since) {
+			continue
+		}
+		filtered = append(filtered, info)
+	}
+	return filtered, nil
+}
+
+// referenceCreatedTime 返回 before 或 since 过滤条件所指容器的创建时间，没有该条件时返回零值。
+// 过滤条件与其他命令一样可以是完整ID、名称或唯一的ID前缀
+func referenceCreatedTime(containers []*container.Info, psFilters filters.Args, key string) (time.Time, error) {
+	var created time.Time
+	for _, ref := range psFilters.Get(key) {
+		info := findContainer(containers, func(info *container.Info) bool {
+			return info.Id == ref || info.Name == ref
+		})
+		if info == nil {
+			id, err := container.ResolveContainerID(ref)
+			if err != nil {
+				return time.Time{}, err
+			}
+			info = findContainer(containers, func(info *container.Info) bool {
+				return info.Id == id
+			})
+		}
+		if info == nil {
+			return time.Time{}, errors.Errorf("No such container: %s", ref)
+		}
+		created = createdTime(info)
+	}
+	return created, nil
+}
+
+func findContainer(containers []*container.Info, match func(*container.Info) bool) *container.Info {
+	for _, info := range containers {
+		if match(info) {
+			return info
+		}
+	}
+	return nil
 }
@@ -3,8 +3,13 @@ package container
 import (
 	"fmt"
 	"path"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DeJeune/sudocker/cli/opts"
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
 	"github.com/DeJeune/sudocker/runtime/utils"
 )
 
@@ -18,3 +23,61 @@ func TestList(t *testing.T) {
 		t.Errorf("expected %s but %s got", expected, configFileDir)
 	}
 }
+
+func TestFilterContainers(t *testing.T) {
+	now := time.Now()
+	containers := []*container.Info{
+		{Id: "c3", Name: "web", Status: container.Running, ImageName: "nginx",
+			State: container.BaseState{Created: now, Config: config.Config{Labels: map[string]string{"env": "prod"}}}},
+		{Id: "c2", Name: "job", Status: container.Stopped, ExitCode: 1, ImageName: "busybox",
+			State: container.BaseState{Created: now.Add(-time.Minute)}},
+		{Id: "c1", Name: "db", Status: container.Stopped, ExitCode: 0, ImageName: "busybox",
+			State: container.BaseState{Created: now.Add(-2 * time.Minute)}},
+	}
+	tests := []struct {
+		name    string
+		all     bool
+		last    int
+		filters []string
+		want    []string
+	}{
+		{name: "running only", last: -1, want: []string{"c3"}},
+		{name: "all", all: true, last: -1, want: []string{"c3", "c2", "c1"}},
+		{name: "last", last: 2, want: []string{"c3", "c2"}},
+		{name: "status exited", last: -1, filters: []string{"status=exited"}, want: []string{"c2", "c1"}},
+		{name: "exited code", last: -1, filters: []string{"exited=1"}, want: []string{"c2"}},
+		{name: "label", all: true, last: -1, filters: []string{"label=env=prod"}, want: []string{"c3"}},
+		{name: "ancestor", all: true, last: -1, filters: []string{"ancestor=busybox"}, want: []string{"c2", "c1"}},
+		{name: "before", all: true, last: -1, filters: []string{"before=job"}, want: []string{"c1"}},
+		{name: "since", all: true, last: -1, filters: []string{"since=db"}, want: []string{"c3", "c2"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			options := &psOptions{all: tc.all, last: tc.last, filter: opts.NewFilterOpt()}
+			for _, f := range tc.filters {
+				if err := options.filter.Set(f); err != nil {
+					t.Fatal(err)
+				}
+			}
+			filtered, err := filterContainers(containers, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, info := range filtered {
+				got = append(got, info.Id)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("expected %v but %v got", tc.want, got)
+			}
+		})
+	}
+
+	for _, f := range []string{"status=bogus", "exited=abc", "color=red"} {
+		options := &psOptions{last: -1, filter: opts.NewFilterOpt()}
+		_ = options.filter.Set(f)
+		if _, err := filterContainers(containers, options); err == nil {
+			t.Errorf("expected error for filter %s", f)
+		}
+	}
+}
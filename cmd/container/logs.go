@@ -8,6 +8,7 @@ import (
 
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
 	"github.com/DeJeune/sudocker/runtime/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -52,7 +53,11 @@ func NewLogsCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 }
 
 func runLogs(ctx context.Context, sudockerCli *cmd.SudockerCli, opts *logsOptions) error {
-	logFileLocation := fmt.Sprintf(utils.InfoLocFormat, opts.containerId) + utils.GetLogfile(opts.containerId)
+	containerId, err := container.ResolveContainerID(opts.containerId)
+	if err != nil {
+		return err
+	}
+	logFileLocation := fmt.Sprintf(utils.InfoLocFormat, containerId) + utils.GetLogfile(containerId)
 	file, err := os.Open(logFileLocation)
 	if err != nil {
 		return errors.Errorf("Log container open file %s error %v", logFileLocation, err)
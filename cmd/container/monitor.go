@@ -0,0 +1,199 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+// monitorSyncFd 是monitor进程用来向启动者报告init进程是否创建成功的管道
+const monitorSyncFd = 3
+
+const (
+	restartBackoffMin = 100 * time.Millisecond
+	restartBackoffMax = time.Minute
+	// 容器运行超过该时间后才退出，认为上一次启动是成功的，退避时间重新从最小值开始
+	restartBackoffResetAfter = 10 * time.Second
+)
+
+func NewMonitorCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	var daemon bool
+	cmd := &cobra.Command{
+		Use:    "monitor [--daemon] CONTAINER",
+		Short:  "Supervise a container and record its exit status, can't be used outside",
+		Args:   cli.ExactArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !daemon {
+				return forkMonitor(args[0])
+			}
+			return runMonitor(cmd.Context(), sudockerCli, args[0])
+		},
+	}
+	cmd.Flags().BoolVar(&daemon, "daemon", false, "Run as the monitor daemon")
+	return cmd
+}
+
+// startMonitor 在后台启动容器的monitor进程，由它创建容器的init进程，记录容器的退出状态，
+// 并按照重启策略重启容器。返回时容器处于Created状态
+func startMonitor(containerId string) error {
+	readPipe, writePipe, err := os.Pipe()
+	if err != nil {
+		return errors.Errorf("New pipe error %v", err)
+	}
+	defer readPipe.Close()
+	logFileLocation := path.Join(fmt.Sprintf(utils.InfoLocFormat, containerId), utils.MonitorLogName)
+	logFile, err := os.OpenFile(logFileLocation, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		_ = writePipe.Close()
+		return errors.Errorf("open monitor log file %s error %v", logFileLocation, err)
+	}
+	defer logFile.Close()
+
+	monitor := exec.Command("/proc/self/exe", "monitor", containerId)
+	// 脱离当前的会话，sudocker 命令退出后monitor进程继续运行
+	monitor.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	monitor.Stdout = logFile
+	monitor.Stderr = logFile
+	monitor.ExtraFiles = []*os.File{writePipe}
+	if err := monitor.Start(); err != nil {
+		_ = writePipe.Close()
+		return errors.Errorf("Failed to start monitor process: %v", err)
+	}
+	_ = writePipe.Close()
+	// 中间进程启动真正的monitor进程后立即退出，monitor进程被系统的init进程收养，不再是当前进程的子进程
+	waitErr := monitor.Wait()
+	msg, err := io.ReadAll(readPipe)
+	if err != nil {
+		return errors.Errorf("read from monitor process error %v", err)
+	}
+	if len(msg) > 0 {
+		return errors.New(string(msg))
+	}
+	if waitErr != nil {
+		return errors.Errorf("monitor process error %v", waitErr)
+	}
+	return nil
+}
+
+// forkMonitor 是两次fork中的中间进程：启动真正的monitor进程，把同步管道和日志文件交给它，然后退出
+func forkMonitor(containerId string) error {
+	syncPipe := os.NewFile(uintptr(monitorSyncFd), "sync")
+	defer syncPipe.Close()
+	daemon := exec.Command("/proc/self/exe", "monitor", "--daemon", containerId)
+	daemon.Stdout = os.Stdout
+	daemon.Stderr = os.Stderr
+	daemon.ExtraFiles = []*os.File{syncPipe}
+	if err := daemon.Start(); err != nil {
+		_, _ = syncPipe.WriteString(fmt.Sprintf("Failed to start monitor process: %v", err))
+		return err
+	}
+	return daemon.Process.Release()
+}
+
+// runMonitor 是monitor进程的入口：成为容器的subreaper，创建容器的init进程，通过同步管道通知启动者，
+// 然后在控制socket上接受请求并看护容器，直到容器不再需要重启。设置了 --rm 的容器在最后一次退出后被删除
+func runMonitor(ctx context.Context, sudockerCli *cmd.SudockerCli, containerId string) error {
+	syncPipe := os.NewFile(uintptr(monitorSyncFd), "sync")
+	// 不让init进程以及网络配置时执行的命令继承同步管道，否则启动者要等它们退出才能读到EOF
+	unix.CloseOnExec(monitorSyncFd)
+	// 容器进程留下的孤儿进程交给monitor进程回收，而不是系统的init进程
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		logrus.Warnf("set child subreaper error %v", err)
+	}
+
+	m := &containerMonitor{containerId: containerId}
+	var parentProcess *ParentProcess
+	err := m.listen()
+	if err == nil {
+		var info *container.Info
+		if info, err = container.GetInfoByContainerId(containerId); err == nil {
+			info.MonitorPid = os.Getpid()
+			parentProcess, err = m.spawn(ctx, sudockerCli, info)
+		}
+	}
+	if err != nil {
+		m.close()
+		_, _ = syncPipe.WriteString(err.Error())
+		_ = syncPipe.Close()
+		return err
+	}
+	go m.reapOrphans(ctx)
+	_ = syncPipe.Close()
+
+	err = superviseContainer(ctx, sudockerCli, m, parentProcess)
+	m.close()
+	if err != nil {
+		return err
+	}
+	return m.autoRemove()
+}
+
+// superviseContainer 等待容器的init进程退出并记录退出码，然后根据重启策略决定是否重启容器。
+// 连续重启之间按照指数退避等待，容器被删除、被手动停止或被其他进程重新启动后退出
+func superviseContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, m *containerMonitor, parentProcess *ParentProcess) error {
+	containerId := parentProcess.containerId
+	backoff := restartBackoffMin
+	for {
+		startedAt := time.Now()
+		status, info, err := parentProcess.wait()
+		m.exited(status)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			// 容器已经被删除，或者已经被重新启动，由新的monitor进程负责
+			return nil
+		}
+		if !info.HasRestartPolicy() ||
+			!info.HostConfig.RestartPolicy.ShouldRestart(info.ExitCode, info.RestartCount, info.ManuallyStopped) {
+			return nil
+		}
+
+		if time.Since(startedAt) >= restartBackoffResetAfter {
+			backoff = restartBackoffMin
+		}
+		logrus.Infof("Container %s exited with code %d, restarting in %v", containerId, info.ExitCode, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, restartBackoffMax)
+
+		// 等待期间容器可能被删除、停止或者启动，持有锁重新检查容器状态后再增加重启次数
+		restart := false
+		info, err = container.UpdateContainerInfo(containerId, func(info *container.Info) error {
+			if info.Status != container.Stopped || info.ManuallyStopped {
+				return container.ErrSkipUpdate
+			}
+			info.RestartCount++
+			restart = true
+			return nil
+		})
+		if err != nil || !restart {
+			return nil
+		}
+		parentProcess, err = m.spawn(ctx, sudockerCli, info)
+		if err != nil {
+			return errors.WithMessagef(err, "restart container %s", containerId)
+		}
+		if err := container.StartContainer(containerId); err != nil {
+			return err
+		}
+	}
+}
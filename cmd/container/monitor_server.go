@@ -0,0 +1,276 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// monitorRequestTimeout 是控制socket上读取请求的超时时间
+const monitorRequestTimeout = 5 * time.Second
+
+// containerMonitor 是monitor进程的状态：控制socket，以及当前看护的init进程
+type containerMonitor struct {
+	containerId string
+	listener    *net.UnixListener
+	// socket 是创建控制socket时的文件信息，关闭时只删除自己创建的socket
+	socket   os.FileInfo
+	serving  chan struct{}
+	handlers sync.WaitGroup
+	// spawning 在创建init进程和自动删除容器期间持有，此时monitor进程执行的命令不会被reapOrphans抢先回收
+	spawning sync.Mutex
+
+	mu  sync.Mutex
+	run *monitoredRun
+}
+
+// monitoredRun 是容器的一次运行，done在init进程退出并记录退出状态后关闭
+type monitoredRun struct {
+	process  *os.Process
+	stdio    *containerStdio
+	done     chan struct{}
+	exitCode int
+}
+
+// listen 在容器目录下创建控制socket并开始接受请求，上一个monitor进程留下的socket会被替换
+func (m *containerMonitor) listen() error {
+	socketPath := container.MonitorSocketPath(m.containerId)
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Errorf("remove stale monitor socket %s error %v", socketPath, err)
+	}
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		return errors.Errorf("listen on monitor socket %s error %v", socketPath, err)
+	}
+	listener.SetUnlinkOnClose(false)
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		_ = listener.Close()
+		return errors.Errorf("chmod monitor socket %s error %v", socketPath, err)
+	}
+	if m.socket, err = os.Stat(socketPath); err != nil {
+		_ = listener.Close()
+		return errors.Errorf("stat monitor socket %s error %v", socketPath, err)
+	}
+	m.listener = listener
+	m.serving = make(chan struct{})
+	go m.serve()
+	return nil
+}
+
+// close 停止接受请求，等待正在处理的请求完成后删除控制socket
+func (m *containerMonitor) close() {
+	if m.listener == nil {
+		return
+	}
+	_ = m.listener.Close()
+	<-m.serving
+	m.handlers.Wait()
+	socketPath := container.MonitorSocketPath(m.containerId)
+	// 容器可能已经由新的monitor进程重新启动，socket已经被替换
+	if fi, err := os.Stat(socketPath); err == nil && os.SameFile(fi, m.socket) {
+		_ = os.Remove(socketPath)
+	}
+}
+
+func (m *containerMonitor) serve() {
+	defer close(m.serving)
+	for {
+		conn, err := m.listener.AcceptUnix()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logrus.Errorf("accept on monitor socket error %v", err)
+			}
+			return
+		}
+		m.handlers.Add(1)
+		go func() {
+			defer m.handlers.Done()
+			m.handle(conn)
+		}()
+	}
+}
+
+// handle 处理一个连接上的请求，每个连接只有一个请求
+func (m *containerMonitor) handle(conn *net.UnixConn) {
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(monitorRequestTimeout))
+	var req container.MonitorRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		logrus.Warnf("decode monitor request error %v", err)
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	if req.Command == container.MonitorCommandAttach {
+		m.attach(conn, req)
+		return
+	}
+	if err := json.NewEncoder(conn).Encode(m.handleRequest(req)); err != nil {
+		logrus.Warnf("send monitor response error %v", err)
+	}
+}
+
+func (m *containerMonitor) handleRequest(req container.MonitorRequest) container.MonitorResponse {
+	resp := container.MonitorResponse{MonitorPid: os.Getpid()}
+	run := m.current()
+	if run == nil {
+		resp.Error = fmt.Sprintf("container %s has no init process", m.containerId)
+		return resp
+	}
+	resp.Pid = run.process.Pid
+	switch req.Command {
+	case container.MonitorCommandState:
+	case container.MonitorCommandSignal:
+		// init进程已经被回收时返回错误，不会把信号发给复用了pid的其他进程
+		if err := run.process.Signal(unix.Signal(req.Signal)); err != nil {
+			resp.Error = fmt.Sprintf("signal container %s error %v", m.containerId, err)
+		}
+	case container.MonitorCommandWait:
+		<-run.done
+		resp.ExitCode = run.exitCode
+	case container.MonitorCommandResize:
+		if err := run.stdio.resize(req.Height, req.Width); err != nil {
+			resp.Error = err.Error()
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown monitor command %q", req.Command)
+	}
+	return resp
+}
+
+// attach 响应attach请求后，将连接交给容器当前运行的标准输入输出，直到容器退出或者客户端断开
+func (m *containerMonitor) attach(conn *net.UnixConn, req container.MonitorRequest) {
+	resp := container.MonitorResponse{MonitorPid: os.Getpid()}
+	run := m.current()
+	if run == nil || run.finished() {
+		resp.Error = fmt.Sprintf("container %s is not running", m.containerId)
+	} else {
+		resp.Pid = run.process.Pid
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil || resp.Error != "" {
+		return
+	}
+	run.stdio.attach(conn, req.Stdin)
+}
+
+func (r *monitoredRun) finished() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *containerMonitor) current() *monitoredRun {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.run
+}
+
+// spawn 为容器创建标准输入输出以及init进程，并记录为当前的运行
+func (m *containerMonitor) spawn(ctx context.Context, sudockerCli *cmd.SudockerCli, info *container.Info) (*ParentProcess, error) {
+	// 创建init进程期间不回收孤儿进程，以免抢先回收了网络配置等命令的子进程
+	m.spawning.Lock()
+	defer m.spawning.Unlock()
+	containerConfig := containerConfigFromInfo(info)
+	stdio, err := newContainerStdio(info.Id, containerConfig.Config)
+	if err != nil {
+		return nil, err
+	}
+	parentProcess, err := initContainer(ctx, sudockerCli, containerConfig, info, stdio)
+	if err != nil {
+		stdio.close()
+		return nil, err
+	}
+	m.mu.Lock()
+	m.run = &monitoredRun{process: parentProcess.cmd.Process, stdio: stdio, done: make(chan struct{})}
+	m.mu.Unlock()
+	return parentProcess, nil
+}
+
+// exited 在容器的输出转发完成后记录当前init进程的退出码，断开attach的客户端并唤醒等待它退出的请求
+func (m *containerMonitor) exited(status container.ExitStatus) {
+	run := m.current()
+	if run == nil {
+		return
+	}
+	run.stdio.close()
+	run.exitCode = status.ExitCode
+	close(run.done)
+}
+
+// reapOrphans 回收托付给monitor进程的孤儿进程。容器的init进程由ParentProcess.wait回收
+func (m *containerMonitor) reapOrphans(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, unix.SIGCHLD)
+	defer signal.Stop(sigs)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigs:
+		}
+		m.spawning.Lock()
+		for _, pid := range m.orphans() {
+			var ws unix.WaitStatus
+			_, _ = unix.Wait4(pid, &ws, unix.WNOHANG, nil)
+		}
+		m.spawning.Unlock()
+	}
+}
+
+// orphans 返回monitor进程除当前init进程以外的子进程
+func (m *containerMonitor) orphans() []int {
+	initPid := 0
+	if run := m.current(); run != nil {
+		initPid = run.process.Pid
+	}
+	files, _ := filepath.Glob("/proc/self/task/*/children")
+	var pids []int
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, field := range strings.Fields(string(content)) {
+			if pid, err := strconv.Atoi(field); err == nil && pid != initPid {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids
+}
+
+// autoRemove 在容器最后一次退出后删除设置了 --rm 的容器。
+// 容器已经被删除，或者已经由其他monitor进程重新启动时不做处理
+func (m *containerMonitor) autoRemove() error {
+	// 释放网络时执行的iptables命令由 exec.Cmd 回收
+	m.spawning.Lock()
+	defer m.spawning.Unlock()
+	info, err := container.GetInfoByContainerId(m.containerId)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if info.Status != container.Stopped || info.MonitorPid != os.Getpid() ||
+		info.HostConfig == nil || !info.HostConfig.AutoRemove {
+		return nil
+	}
+	return removeContainer(info)
+}
@@ -0,0 +1,241 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// attachWriteTimeout 是向attach的客户端转发输出的超时时间，读取太慢的客户端会被断开
+	attachWriteTimeout = 5 * time.Second
+	// stdioDrainTimeout 是容器退出后等待输出转发完成的最长时间
+	stdioDrainTimeout = 2 * time.Second
+)
+
+// containerStdio 是monitor进程为容器的一次运行持有的标准输入输出。容器的输出追加写入日志文件，
+// 同时转发给attach到容器的客户端；客户端的输入写入容器的终端或者标准输入管道
+type containerStdio struct {
+	tty       bool
+	stdinOnce bool
+	log       *os.File
+	// console 是分配了终端时伪终端的master
+	console *os.File
+	// stdin 是打开了标准输入时管道的写端，stdout、stderr 是输出管道的读端
+	stdin, stdout, stderr *os.File
+	// cmdStdin、cmdStdout、cmdStderr 交给init进程，init进程启动后关闭
+	cmdStdin, cmdStdout, cmdStderr *os.File
+	copying                        sync.WaitGroup
+
+	mu      sync.Mutex
+	closed  bool
+	clients map[*attachClient]struct{}
+}
+
+// attachClient 是一个attach到容器的客户端连接
+type attachClient struct {
+	conn      *net.UnixConn
+	stdout    io.Writer
+	stderr    io.Writer
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *attachClient) close() {
+	c.closeOnce.Do(func() {
+		_ = c.conn.Close()
+		close(c.done)
+	})
+}
+
+// newContainerStdio 为容器的一次运行创建标准输入输出：分配了终端时创建伪终端，否则创建输出管道，
+// 打开了标准输入时再创建输入管道
+func newContainerStdio(containerId string, cfg *config.Config) (*containerStdio, error) {
+	logFileLocation := fmt.Sprintf(utils.InfoLocFormat, containerId) + utils.GetLogfile(containerId)
+	// 重新启动已停止的容器时追加写入，保留之前的日志
+	logFile, err := os.OpenFile(logFileLocation, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, errors.Errorf("open log file %s error %v", logFileLocation, err)
+	}
+	s := &containerStdio{
+		tty:       cfg.Tty,
+		stdinOnce: cfg.StdinOnce,
+		log:       logFile,
+		clients:   make(map[*attachClient]struct{}),
+	}
+	if cfg.Tty {
+		master, slave, err := container.NewConsole()
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		s.console = master
+		s.cmdStdin, s.cmdStdout, s.cmdStderr = slave, slave, slave
+		return s, nil
+	}
+	if s.stdout, s.cmdStdout, err = os.Pipe(); err != nil {
+		s.close()
+		return nil, errors.Errorf("New pipe error %v", err)
+	}
+	if s.stderr, s.cmdStderr, err = os.Pipe(); err != nil {
+		s.close()
+		return nil, errors.Errorf("New pipe error %v", err)
+	}
+	if cfg.OpenStdin {
+		if s.cmdStdin, s.stdin, err = os.Pipe(); err != nil {
+			s.close()
+			return nil, errors.Errorf("New pipe error %v", err)
+		}
+	}
+	return s, nil
+}
+
+// apply 将标准输入输出设置给init进程，分配了终端时init进程以伪终端作为控制终端
+func (s *containerStdio) apply(cmd *exec.Cmd) {
+	if s.cmdStdin != nil {
+		cmd.Stdin = s.cmdStdin
+	}
+	cmd.Stdout = s.cmdStdout
+	cmd.Stderr = s.cmdStderr
+	if s.tty {
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+		cmd.SysProcAttr.Ctty = 0
+	}
+}
+
+// started 在init进程启动后关闭交给它的一端，并开始转发容器的输出
+func (s *containerStdio) started() {
+	s.closeCmdFiles()
+	if s.tty {
+		s.copying.Add(1)
+		go s.copyOutput(stdcopy.Stdout, s.console)
+		return
+	}
+	s.copying.Add(2)
+	go s.copyOutput(stdcopy.Stdout, s.stdout)
+	go s.copyOutput(stdcopy.Stderr, s.stderr)
+}
+
+// copyOutput 将容器的输出写入日志文件并转发给attach的客户端，直到容器的所有进程都关闭了输出。
+// 伪终端的slave端全部关闭后，读取master返回EIO
+func (s *containerStdio) copyOutput(stream stdcopy.StdType, r io.Reader) {
+	defer s.copying.Done()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			s.broadcast(stream, buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *containerStdio) broadcast(stream stdcopy.StdType, p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.log.Write(p); err != nil {
+		logrus.Warnf("write container log error %v", err)
+	}
+	for c := range s.clients {
+		w := c.stdout
+		if stream == stdcopy.Stderr {
+			w = c.stderr
+		}
+		_ = c.conn.SetWriteDeadline(time.Now().Add(attachWriteTimeout))
+		if _, err := w.Write(p); err != nil {
+			// 客户端已经断开，或者读取太慢
+			delete(s.clients, c)
+			c.close()
+		}
+	}
+}
+
+// attach 将客户端连接加入容器输出的转发，stdin为true时把客户端的输入写入容器。
+// 阻塞直到这次运行结束或者客户端断开
+func (s *containerStdio) attach(conn *net.UnixConn, stdin bool) {
+	c := &attachClient{conn: conn, stdout: conn, stderr: conn, done: make(chan struct{})}
+	if !s.tty {
+		c.stdout = stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+		c.stderr = stdcopy.NewStdWriter(conn, stdcopy.Stderr)
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	var input io.Writer = io.Discard
+	switch {
+	case !stdin:
+	case s.tty:
+		input = s.console
+	case s.stdin != nil:
+		input = s.stdin
+	}
+	_, _ = io.Copy(input, conn)
+	// 客户端关闭了输入，StdinOnce 的容器随之关闭标准输入
+	if stdin && s.stdinOnce && s.stdin != nil {
+		_ = s.stdin.Close()
+	}
+	<-c.done
+}
+
+// resize 修改容器终端的大小
+func (s *containerStdio) resize(height, width uint) error {
+	if s.console == nil {
+		return errors.New("the container was not created with a TTY")
+	}
+	return container.ResizeConsole(s.console, height, width)
+}
+
+// close 等待容器的输出转发完成后，断开所有attach的客户端并关闭文件
+func (s *containerStdio) close() {
+	drained := make(chan struct{})
+	go func() {
+		s.copying.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(stdioDrainTimeout):
+		logrus.Warnf("container output is still open after %v, stop forwarding", stdioDrainTimeout)
+	}
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		c.close()
+	}
+	s.clients = nil
+	s.mu.Unlock()
+
+	s.closeCmdFiles()
+	for _, f := range []*os.File{s.console, s.stdin, s.stdout, s.stderr, s.log} {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+}
+
+func (s *containerStdio) closeCmdFiles() {
+	for _, f := range []*os.File{s.cmdStdin, s.cmdStdout, s.cmdStderr} {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+}
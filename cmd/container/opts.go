@@ -1,7 +1,10 @@
 package container
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -9,6 +12,10 @@ import (
 	"github.com/DeJeune/sudocker/cli/compose/loader"
 	"github.com/DeJeune/sudocker/cli/opts"
 	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/capabilites"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/pkg/seccomp"
+	"github.com/moby/sys/signal"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
@@ -18,12 +25,20 @@ var deviceCgroupRuleRegexp = regexp.MustCompile(`^[acb] ([0-9]+|\*):([0-9]+|\*)
 type containerOptions struct {
 	hostname   string
 	domainname string
+	user       string
+	workingDir string
+	groupAdd   opts.ListOpts
+	capAdd     opts.ListOpts
+	capDrop    opts.ListOpts
+	privileged bool
 	attach     opts.ListOpts
 	volumes    opts.ListOpts
 	stdin      bool
 	tty        bool
 	env        opts.ListOpts
 	envFile    opts.ListOpts
+	labels     opts.ListOpts
+	labelsFile opts.ListOpts
 	// devices            opts.ListOpts
 	// deviceCgroupRules  opts.ListOpts
 	// blkioWeightDevice  opts.WeightdeviceOpt
@@ -51,23 +66,35 @@ type containerOptions struct {
 	blkioWeight        uint16
 	// ioMaxBandwidth     opts.MemBytes
 	// ioMaxIOps          uint64
-	swappiness int64
-	publish    opts.ListOpts
-	expose     opts.ListOpts
-	netMode    string
-	autoRemove bool
-	Image      string
-	Args       []string
+	swappiness    int64
+	securityOpt   opts.ListOpts
+	ulimits       *opts.UlimitOpt
+	publish       opts.ListOpts
+	expose        opts.ListOpts
+	netMode       string
+	autoRemove    bool
+	restartPolicy string
+	stopSignal    string
+	stopTimeout   int
+	Image         string
+	Args          []string
 }
 
 func addFlags(flags *pflag.FlagSet) *containerOptions {
 	copts := &containerOptions{
-		attach:  opts.NewListOpts(validateAttach),
-		volumes: opts.NewListOpts(nil),
-		env:     opts.NewListOpts(opts.ValidateEnv),
-		envFile: opts.NewListOpts(nil),
-		publish: opts.NewListOpts(nil),
-		expose:  opts.NewListOpts(nil),
+		attach:      opts.NewListOpts(validateAttach),
+		volumes:     opts.NewListOpts(nil),
+		env:         opts.NewListOpts(opts.ValidateEnv),
+		envFile:     opts.NewListOpts(nil),
+		labels:      opts.NewListOpts(opts.ValidateLabel),
+		labelsFile:  opts.NewListOpts(nil),
+		publish:     opts.NewListOpts(nil),
+		expose:      opts.NewListOpts(nil),
+		groupAdd:    opts.NewListOpts(nil),
+		capAdd:      opts.NewListOpts(nil),
+		capDrop:     opts.NewListOpts(nil),
+		securityOpt: opts.NewListOpts(nil),
+		ulimits:     opts.NewUlimitOpt(nil),
 	}
 	// General purpose flags
 	flags.VarP(&copts.attach, "attach", "a", "Attach to STDIN, STDOUT or STDERR")
@@ -76,10 +103,24 @@ func addFlags(flags *pflag.FlagSet) *containerOptions {
 	flags.VarP(&copts.volumes, "volume", "v", "Bind mount a volume")
 	flags.VarP(&copts.env, "env", "e", "Set environment variables")
 	flags.Var(&copts.envFile, "env-file", "Read in a file of environment variables")
+	flags.VarP(&copts.labels, "label", "l", "Set meta data on a container")
+	flags.Var(&copts.labelsFile, "label-file", "Read in a line delimited file of labels")
 	flags.StringVarP(&copts.hostname, "hostname", "h", "", "Container host name")
 	flags.StringVar(&copts.domainname, "domainname", "", "Container NIS domain name")
+	flags.StringVarP(&copts.user, "user", "u", "", "Username or UID (format: <name|uid>[:<group|gid>])")
+	flags.Var(&copts.groupAdd, "group-add", "Add additional groups to join")
+	flags.StringVarP(&copts.workingDir, "workdir", "w", "", "Working directory inside the container")
 	flags.BoolVar(&copts.autoRemove, "rm", false, "Automatically remove the container and its associated anonymous volumes when it exits")
+	flags.StringVar(&copts.restartPolicy, "restart", string(config.RestartPolicyDisabled), "Restart policy to apply when a container exits")
+	flags.StringVar(&copts.stopSignal, "stop-signal", "", "Signal to stop the container")
+	flags.IntVar(&copts.stopTimeout, "stop-timeout", 0, "Timeout (in seconds) to stop a container")
+	// Security
+	flags.Var(&copts.capAdd, "cap-add", "Add Linux capabilities")
+	flags.Var(&copts.capDrop, "cap-drop", "Drop Linux capabilities")
+	flags.BoolVar(&copts.privileged, "privileged", false, "Give extended privileges to this container")
+	flags.Var(&copts.securityOpt, "security-opt", "Security Options")
 	// Resource management
+	flags.Var(copts.ulimits, "ulimit", "Ulimit options")
 	flags.Uint16Var(&copts.blkioWeight, "blkio-weight", 0, "Block IO (relative weight), between 10 and 1000, or 0 to disable (default 0)")
 	// flags.Var(&copts.blkioWeightDevice, "blkio-weight-device", "Block IO weight (relative device weight)")
 	// flags.StringVar(&copts.containerIDFile, "cidfile", "", "Write the container ID to the file")
@@ -184,15 +225,61 @@ func parse(flags *pflag.FlagSet, copts *containerOptions) (*containerConfig, err
 		runCmd = copts.Args
 	}
 
+	// 解析 -w，工作目录必须是容器内的绝对路径
+	if copts.workingDir != "" && !filepath.IsAbs(copts.workingDir) {
+		return nil, errors.Errorf("the working directory '%s' is invalid, it needs to be an absolute path", copts.workingDir)
+	}
+
+	// 解析 --cap-add 和 --cap-drop
+	capAdd, err := capabilites.NormalizeCapabilities(copts.capAdd.GetAll())
+	if err != nil {
+		return nil, err
+	}
+	capDrop, err := capabilites.NormalizeCapabilities(copts.capDrop.GetAll())
+	if err != nil {
+		return nil, err
+	}
+
+	securityOpts, err := parseSecurityOpts(copts.securityOpt.GetAll())
+	if err != nil {
+		return nil, err
+	}
+
 	// 解析 -p
 	publishOpts := copts.publish.GetAll()
 
+	// 解析 --stop-signal 和 --stop-timeout
+	if copts.stopSignal != "" {
+		if _, err := signal.ParseSignal(copts.stopSignal); err != nil {
+			return nil, err
+		}
+	}
+	var stopTimeout *int
+	if flags.Changed("stop-timeout") {
+		stopTimeout = &copts.stopTimeout
+	}
+
+	// 解析 --restart
+	restartPolicy, err := opts.ParseRestartPolicy(copts.restartPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if copts.autoRemove && !restartPolicy.IsNone() {
+		return nil, errors.Errorf("Conflicting options: --restart and --rm")
+	}
+
 	// 解析 -e 参数
 	envVariables, err := opts.ReadKVEnvStrings(copts.envFile.GetAll(), copts.env.GetAll())
 	if err != nil {
 		return nil, err
 	}
 
+	// 解析 --label 和 --label-file
+	labels, err := opts.ReadKVStrings(copts.labelsFile.GetAll(), copts.labels.GetAll())
+	if err != nil {
+		return nil, err
+	}
+
 	resources := config.Resources{
 		Memory:            copts.memory.Value(),
 		MemoryReservation: copts.memoryReservation.Value(),
@@ -212,21 +299,34 @@ func parse(flags *pflag.FlagSet, copts *containerOptions) (*containerConfig, err
 	generalConfig := &config.Config{
 		Hostname:     copts.hostname,
 		Domainname:   copts.domainname,
+		User:         copts.user,
 		OpenStdin:    copts.stdin,
 		AttachStdin:  attachStdin,
+		StdinOnce:    attachStdin,
 		AttachStdout: attachStdout,
 		AttachStderr: attachStderr,
 		Cmd:          runCmd,
 		Image:        copts.Image,
 		Tty:          copts.tty,
 		Env:          envVariables,
+		WorkingDir:   copts.workingDir,
+		Labels:       opts.ConvertKVStringsToMap(labels),
+		StopSignal:   copts.stopSignal,
+		StopTimeout:  stopTimeout,
 	}
 
 	hostConfig := &config.HostConfig{
-		Binds:        binds,
-		Resources:    &resources,
-		PortBindings: publishOpts,
-		AutoRemove:   copts.autoRemove,
+		Binds:         binds,
+		GroupAdd:      copts.groupAdd.GetAll(),
+		CapAdd:        capAdd,
+		CapDrop:       capDrop,
+		Privileged:    copts.privileged,
+		SecurityOpt:   securityOpts,
+		Ulimits:       copts.ulimits.GetList(),
+		Resources:     &resources,
+		PortBindings:  publishOpts,
+		AutoRemove:    copts.autoRemove,
+		RestartPolicy: restartPolicy,
 	}
 
 	networkingConfig := &config.NetworkingConfig{
@@ -240,6 +340,34 @@ func parse(flags *pflag.FlagSet, copts *containerOptions) (*containerConfig, err
 	}, nil
 }
 
+// parseSecurityOpts 校验 --security-opt 并统一为 key=value 的形式，seccomp的配置文件被替换为文件的内容，
+// 之后配置文件被修改或删除不影响容器
+func parseSecurityOpts(securityOpts []string) ([]string, error) {
+	parsed := make([]string, 0, len(securityOpts))
+	for _, opt := range securityOpts {
+		key, value, err := container.SplitSecurityOpt(opt)
+		if err != nil {
+			return nil, err
+		}
+		if key == container.SecurityOptSeccomp && value != container.SeccompUnconfined {
+			profile, err := os.ReadFile(value)
+			if err != nil {
+				return nil, errors.Errorf("opening seccomp profile (%s) failed: %v", value, err)
+			}
+			if _, err := seccomp.ParseProfile(profile); err != nil {
+				return nil, errors.WithMessagef(err, "invalid seccomp profile %s", value)
+			}
+			b := bytes.NewBuffer(nil)
+			if err := json.Compact(b, profile); err != nil {
+				return nil, errors.Errorf("compacting json for seccomp profile (%s) failed: %v", value, err)
+			}
+			value = b.String()
+		}
+		parsed = append(parsed, key+"="+value)
+	}
+	return parsed, nil
+}
+
 func validateAttach(val string) (string, error) {
 	s := strings.ToLower(val)
 	for _, str := range []string{"stdin", "stdout", "stderr"} {
@@ -0,0 +1,36 @@
+package container
+
+import (
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/DeJeune/sudocker/runtime/pkg/network"
+	"github.com/sirupsen/logrus"
+)
+
+// ReconcileContainers 将init进程已经不存在的容器标记为已停止，卸载它们残留的挂载，
+// 并释放它们的veth设备和IP地址。返回被修正的容器
+func ReconcileContainers() ([]*container.Info, error) {
+	reconciled, err := container.ReconcileContainers()
+	for _, info := range reconciled {
+		releaseNetwork(info)
+	}
+	return reconciled, err
+}
+
+// releaseStale 释放 stop、kill 发现init进程已经不存在的容器的网络资源，返回err
+func releaseStale(stale *container.Info, err error) error {
+	if stale != nil {
+		releaseNetwork(stale)
+	}
+	return err
+}
+
+// releaseNetwork 释放容器记录中的veth设备、端口映射和IP地址，并清空info中的IP，避免重复释放
+func releaseNetwork(info *container.Info) {
+	if info.IP == "" {
+		return
+	}
+	if err := network.Disconnect(containerNetwork(info), info); err != nil {
+		logrus.Warnf("release network of container %s: %v", info.Id, err)
+	}
+	info.IP = ""
+}
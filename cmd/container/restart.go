@@ -1,8 +1,14 @@
 package container
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -23,11 +29,39 @@ func NewRestartCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.containers = args
 			opts.nSecondsChanged = cmd.Flags().Changed("time")
-			return nil
+			return runRestart(cmd.Context(), sudockerCli, &opts)
+		},
+		Annotations: map[string]string{
+			"aliases": "sudocker container restart, sudocker restart",
 		},
 	}
 
 	flags := cmd.Flags()
-	flags.IntVarP(&opts.nSeconds, "time", "t", 10, "Seconds to wait for stop before killing the container")
+	flags.IntVarP(&opts.nSeconds, "time", "t", container.DefaultStopTimeout, "Seconds to wait for stop before killing the container")
 	return cmd
 }
+
+// runRestart 依次停止并重新启动容器，没有运行的容器直接启动
+func runRestart(ctx context.Context, sudockerCli *cmd.SudockerCli, opts *restartOptions) error {
+	// 没有指定 --time 时使用每个容器自己的 stop timeout
+	var timeout *int
+	if opts.nSecondsChanged {
+		timeout = &opts.nSeconds
+	}
+	var errs []string
+	for _, containerId := range opts.containers {
+		if err := releaseStale(container.StopContainer(containerId, timeout)); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := startContainer(ctx, sudockerCli, containerId); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		_, _ = fmt.Fprintln(sudockerCli.Out(), containerId)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -47,14 +47,31 @@ func NewRmCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	return cmd
 }
 
+// removeContainer 删除已经停止的容器并释放它的网络资源，用于 --rm 的自动清理
+func removeContainer(info *container.Info) error {
+	if err := container.RmContainer(info.Id, container.RemoveOptions{}); err != nil {
+		return err
+	}
+	releaseNetwork(info)
+	return nil
+}
+
 func runRm(ctx context.Context, sudockerCli cmd.Cli, opts *rmOptions) error {
 	var errs []string
 	errChan := parallelOperation(ctx, opts.containers, func(ctx context.Context, containerId string) error {
-		return container.RmContainer(containerId, container.RemoveOptions{
+		// 删除之前读取容器记录，删除成功后据此释放容器的网络资源
+		info, _ := container.GetInfoByContainerId(containerId)
+		if err := container.RmContainer(containerId, container.RemoveOptions{
 			Force:         opts.force,
 			RemoveVolumes: opts.rmVolumes,
 			RemoveLinks:   opts.rmLink,
-		})
+		}); err != nil {
+			return err
+		}
+		if info != nil {
+			releaseNetwork(info)
+		}
+		return nil
 	})
 
 	for _, name := range opts.containers {
@@ -2,20 +2,16 @@ package container
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"path"
 	"strings"
 	"syscall"
 
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cmd"
 	"github.com/DeJeune/sudocker/runtime/pkg/container"
-	"github.com/DeJeune/sudocker/runtime/utils"
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -53,6 +49,8 @@ func NewRunCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	// Here you will define your flags and configuration settings.
 	flags.BoolVarP(&options.detach, "detach", "d", false, "Run the container in the background")
 	flags.StringVarP(&options.name, "name", "n", "", "Assign a name to the container")
+	flags.StringVar(&options.detachKeys, "detach-keys", "", "Override the key sequence for detaching a container")
+	flags.BoolVar(&options.sigProxy, "sig-proxy", true, "Proxy received signals to the process")
 	copts = addFlags(flags)
 	runCmd.RegisterFlagCompletionFunc(
 		"env",
@@ -84,10 +82,16 @@ func runContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, runOpts *ru
 	config := containerCfg.Config
 	stdout, stderr := sudockerCli.Out(), sudockerCli.Err()
 
+	var detachKeys []byte
 	if !runOpts.detach {
 		if err := sudockerCli.In().CheckTty(config.AttachStdin, config.Tty); err != nil {
 			return err
 		}
+		var err error
+		if detachKeys, err = parseDetachKeys(sudockerCli, runOpts.detachKeys); err != nil {
+			reportError(stderr, "run", err.Error(), true)
+			return cli.StatusError{StatusCode: 125}
+		}
 	} else {
 		if copts.attach.Len() != 0 {
 			return errors.Errorf("Conflicting options: -a and -d")
@@ -100,117 +104,56 @@ func runContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, runOpts *ru
 	}
 	ctx, cancelFun := context.WithCancel(ctx)
 	defer cancelFun()
-	parentProcess, err := newContainer(ctx, sudockerCli, containerCfg, &runOpts.createOptions)
+	containerId, err := newContainer(ctx, sudockerCli, containerCfg, &runOpts.createOptions)
 	if err != nil {
 		reportError(stderr, "run", err.Error(), true)
 		return runStartContainerErr(err)
 	}
 
-	containerId := parentProcess.containerId
-	hostConfig := containerCfg.HostConfig
-	parent := parentProcess.cmd
-	statusChan := make(chan string)
-	go func() {
-		if !config.Tty {
-			_, _ = parent.Process.Wait()
-			// 修改容器信息
-			containerInfo, err := container.GetInfoByContainerId(containerId)
-			if err != nil {
-				logrus.Errorf("Get container %s info error %v", containerId, err)
-			}
-			containerInfo.Status = container.Stopped
-			containerInfo.Pid = ""
-			newContentBytes, err := json.Marshal(containerInfo)
-			if err != nil {
-				logrus.Errorf("Json marshal %s error %v", containerId, err)
-			}
-			dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
-			configFilePath := path.Join(dirPath, utils.ConfigName)
-			if err = os.WriteFile(configFilePath, newContentBytes, 0o622); err != nil {
-				logrus.Errorf("Write file %s error:%v", configFilePath, err)
-			}
-			if hostConfig.AutoRemove {
-				statusChan <- "stopped"
-			}
-		}
-		// --rm 在容器退出后清理容器
-	}()
-	if config.Tty {
-		if err := parent.Wait(); err != nil {
-			return errors.Errorf("Parent process failed: %v", err)
-		}
-		// 修改容器信息
-		containerInfo, err := container.GetInfoByContainerId(containerId)
-		if err != nil {
-			return errors.Errorf("Get container %s info error %v", containerId, err)
-		}
-		containerInfo.Status = container.Stopped
-		containerInfo.Pid = ""
-		newContentBytes, err := json.Marshal(containerInfo)
-		if err != nil {
-			return errors.Errorf("Json marshal %s error %v", containerId, err)
-		}
-		dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
-		configFilePath := path.Join(dirPath, utils.ConfigName)
-		if err = os.WriteFile(configFilePath, newContentBytes, 0o622); err != nil {
-			return errors.Errorf("Write file %s error:%v", configFilePath, err)
-		}
-		if hostConfig.AutoRemove {
-			statusChan <- "stopped"
+	if runOpts.detach {
+		if err := container.StartContainer(containerId); err != nil {
+			reportError(stderr, "run", err.Error(), false)
+			return runStartContainerErr(err)
 		}
+		_, _ = fmt.Fprintln(stdout, containerId)
+		return nil
 	}
-	// logrus.Infof("auto remove: %v", copts.autoRemove)
-	if hostConfig.AutoRemove {
-		go func() {
-			for {
-				select {
-				case status := <-statusChan:
-					if status == "stopped" {
-						containerInfo, err := container.GetInfoByContainerId(containerId)
-						if err != nil {
-							logrus.Errorf("Failed to get container info: %v", err)
-							return
-						}
-						if containerInfo.Status == container.Stopped {
-							// 容器已经停止，执行删除操作
-							if err := container.DeleteStorageDriver(containerId, hostConfig.Binds); err != nil {
-								logrus.Errorf("Umount volumes failed: %v", err)
-							}
-							if err := container.DeleteContainerInfo(containerId); err != nil {
-								logrus.Errorf("Delete container info failed: %v", err)
-							}
-							parentProcess.cgroupManager.Destroy()
-							return
-						}
-					}
-				}
-			}
 
-		}()
+	info, err := container.GetInfoByContainerId(containerId)
+	if err != nil {
+		return err
 	}
-
-	//
-	var (
-		waitDisplayID chan struct{}
-	)
-	if !config.AttachStdout && !config.AttachStderr {
-		// Make this asynchronous to allow the client to write to stdin before having to read the ID
-		waitDisplayID = make(chan struct{})
-		go func() {
-			defer close(waitDisplayID)
-			_, _ = fmt.Fprintln(stdout, containerId)
-		}()
+	attached := attachedStreams{
+		tty:        config.Tty,
+		stdin:      config.AttachStdin,
+		detachKeys: detachKeys,
 	}
-
-	if (config.AttachStdin || config.AttachStdout || config.AttachStderr) && config.Tty && sudockerCli.Out().IsTerminal() {
-		if err := MonitorTtySize(ctx, sudockerCli, containerId, false); err != nil {
-			_, _ = fmt.Fprintln(stderr, "Error monitoring TTY size:", err)
-		}
+	if config.AttachStdout {
+		attached.stdout = stdout
+	}
+	if config.AttachStderr {
+		attached.stderr = stderr
 	}
-	if !config.AttachStdout && !config.AttachStderr {
-		// Detached mode
-		<-waitDisplayID
+	// 分配了终端时，Ctrl-C 等按键由容器的终端处理，不需要转发信号
+	if runOpts.sigProxy && !config.Tty {
+		defer proxySignals(ctx, containerId)()
+	}
+	// 先连接到monitor进程再启动容器，不会丢失容器最开始的输出；--rm 由monitor进程在容器退出后处理
+	exitCode, err := attachContainer(ctx, sudockerCli, info, attached, func() error {
+		if err := container.StartContainer(containerId); err != nil {
+			reportError(stderr, "run", err.Error(), false)
+			return runStartContainerErr(err)
+		}
+		if !config.AttachStdout && !config.AttachStderr {
+			_, _ = fmt.Fprintln(stdout, containerId)
+		}
 		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return cli.StatusError{StatusCode: exitCode}
 	}
 	return nil
 }
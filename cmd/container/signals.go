@@ -0,0 +1,63 @@
+package container
+
+import (
+	"context"
+	"os"
+	"syscall"
+
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/moby/sys/signal"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// proxySignals 捕获CLI收到的所有信号并转发给容器的init进程，返回停止转发的函数
+func proxySignals(ctx context.Context, containerId string) func() {
+	sigc := make(chan os.Signal, 128)
+	signal.CatchAll(sigc)
+	go ForwardAllSignals(ctx, containerId, sigc)
+	return func() {
+		signal.StopCatch(sigc)
+	}
+}
+
+// ForwardAllSignals 将sigc中收到的信号通过monitor进程转发给容器的init进程，直到ctx结束或者sigc被关闭
+func ForwardAllSignals(ctx context.Context, containerId string, sigc <-chan os.Signal) {
+	for {
+		var s os.Signal
+		select {
+		case <-ctx.Done():
+			return
+		case sig, ok := <-sigc:
+			if !ok {
+				return
+			}
+			s = sig
+		}
+		// SIGURG 由Go运行时用于抢占goroutine，不是用户发送的信号
+		if s == signal.SIGCHLD || s == signal.SIGPIPE || s == unix.SIGURG {
+			continue
+		}
+		sig, ok := lookupSignal(s)
+		if !ok {
+			logrus.Debugf("Unsupported signal: %v. Discarding.", s)
+			continue
+		}
+		if _, err := container.CallMonitor(ctx, containerId, container.MonitorRequest{
+			Command: container.MonitorCommandSignal,
+			Signal:  int(sig),
+		}); err != nil {
+			logrus.Debugf("Error sending signal %s to container %s: %v", s, containerId, err)
+		}
+	}
+}
+
+// lookupSignal 在 moby/sys/signal 的信号表中查找信号，只转发其中有名称的信号
+func lookupSignal(s os.Signal) (syscall.Signal, bool) {
+	for _, sig := range signal.SignalMap {
+		if sig == s {
+			return sig, true
+		}
+	}
+	return 0, false
+}
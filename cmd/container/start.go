@@ -1,14 +1,22 @@
 package container
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/DeJeune/sudocker/cli"
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 type startOptions struct {
 	attach        bool
 	openStdin     bool
+	sigProxy      bool
 	detachKeys    string
 	checkpoint    string
 	checkpointDir string
@@ -25,7 +33,10 @@ func NewStartCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 		Args:  cli.RequiresMinArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			opts.containers = args
-			return nil
+			return runStart(cmd.Context(), sudockerCli, &opts)
+		},
+		Annotations: map[string]string{
+			"aliases": "sudocker container start, sudocker start",
 		},
 	}
 
@@ -33,6 +44,7 @@ func NewStartCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	flags.BoolVarP(&opts.attach, "attach", "a", false, "Attach STDOUT/STDERR and forward signals")
 	flags.BoolVarP(&opts.openStdin, "interactive", "i", false, "Attach container's STDIN")
 	flags.StringVar(&opts.detachKeys, "detach-keys", "", "Override the key sequence for detaching a container")
+	flags.BoolVar(&opts.sigProxy, "sig-proxy", true, "Proxy received signals to the process when attached")
 
 	flags.StringVar(&opts.checkpoint, "checkpoint", "", "Restore from this checkpoint")
 	flags.SetAnnotation("checkpoint", "experimental", nil)
@@ -40,3 +52,146 @@ func NewStartCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	flags.SetAnnotation("checkpoint-dir", "experimental", nil)
 	return cmd
 }
+
+func runStart(ctx context.Context, sudockerCli *cmd.SudockerCli, opts *startOptions) error {
+	if opts.attach || opts.openStdin {
+		if len(opts.containers) > 1 {
+			return errors.New("you cannot start and attach multiple containers at once")
+		}
+		return startAndAttach(ctx, sudockerCli, opts)
+	}
+
+	var failedContainers []string
+	for _, containerId := range opts.containers {
+		if err := startContainer(ctx, sudockerCli, containerId); err != nil {
+			_, _ = fmt.Fprintln(sudockerCli.Err(), err)
+			failedContainers = append(failedContainers, containerId)
+			continue
+		}
+		_, _ = fmt.Fprintln(sudockerCli.Out(), containerId)
+	}
+	if len(failedContainers) > 0 {
+		return errors.Errorf("Error: failed to start containers: %s", strings.Join(failedContainers, ", "))
+	}
+	return nil
+}
+
+// startContainer 启动一个Created或Stopped状态的容器，已经在运行的容器直接忽略
+func startContainer(ctx context.Context, sudockerCli *cmd.SudockerCli, ref string) error {
+	info, err := prepareContainer(ref)
+	if err != nil {
+		return err
+	}
+	if info.Status == container.Running {
+		return nil
+	}
+	return container.StartContainer(info.Id)
+}
+
+// prepareContainer 为启动容器做准备，返回准备好之后的容器信息。
+// 对于Stopped状态的容器，会由新的monitor进程在原有的rootfs上重新创建init进程，容器回到Created状态
+func prepareContainer(ref string) (*container.Info, error) {
+	info, err := container.GetInfoByContainerId(ref)
+	if err != nil {
+		return nil, errors.Errorf("Get container %s info error %v", ref, err)
+	}
+	containerId := info.Id
+	if info.Status == container.Running {
+		return info, nil
+	}
+	// 用户手动启动容器后，重启策略重新生效
+	if info.ManuallyStopped || info.RestartCount != 0 {
+		if info, err = container.UpdateContainerInfo(containerId, func(info *container.Info) error {
+			info.ManuallyStopped = false
+			info.RestartCount = 0
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	switch info.Status {
+	case container.Stopped:
+		if err := startMonitor(containerId); err != nil {
+			return nil, errors.WithMessagef(err, "restart container %s", containerId)
+		}
+		return container.GetInfoByContainerId(containerId)
+	case container.Created:
+		return info, nil
+	default:
+		return nil, errors.Errorf("cannot start container %s in %s status", containerId, info.Status)
+	}
+}
+
+// startAndAttach 连接到容器的标准输入输出后启动容器，直到容器退出或者用户detach。命令以容器的退出码退出
+func startAndAttach(ctx context.Context, sudockerCli *cmd.SudockerCli, opts *startOptions) error {
+	info, err := prepareContainer(opts.containers[0])
+	if err != nil {
+		return err
+	}
+	if !info.Monitored() {
+		return errors.Errorf("container %s is not supervised by a monitor process and can't be attached", info.Id)
+	}
+	cfg := info.State.Config
+	attachStdin := opts.openStdin && cfg.OpenStdin
+	if err := sudockerCli.In().CheckTty(attachStdin, cfg.Tty); err != nil {
+		return err
+	}
+	detachKeys, err := parseDetachKeys(sudockerCli, opts.detachKeys)
+	if err != nil {
+		return err
+	}
+	if opts.sigProxy && !cfg.Tty {
+		defer proxySignals(ctx, info.Id)()
+	}
+	var start func() error
+	if info.Status != container.Running {
+		start = func() error {
+			return container.StartContainer(info.Id)
+		}
+	}
+	exitCode, err := attachContainer(ctx, sudockerCli, info, attachedStreams{
+		tty:        cfg.Tty,
+		stdin:      attachStdin,
+		stdout:     sudockerCli.Out(),
+		stderr:     sudockerCli.Err(),
+		detachKeys: detachKeys,
+	}, start)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return cli.StatusError{StatusCode: exitCode}
+	}
+	return nil
+}
+
+// containerConfigFromInfo 根据记录的容器信息还原出创建容器时的配置。
+// 旧格式的记录没有保存完整配置，只能根据摘要信息尽量还原
+func containerConfigFromInfo(info *container.Info) *containerConfig {
+	if info.HasFullConfig() {
+		cfg := info.State.Config
+		hostConfig := *info.HostConfig
+		if hostConfig.Resources == nil {
+			hostConfig.Resources = &config.Resources{}
+		}
+		networkingConfig := *info.NetworkingConfig
+		return &containerConfig{
+			Config:           &cfg,
+			HostConfig:       &hostConfig,
+			NetworkingConfig: &networkingConfig,
+		}
+	}
+	return &containerConfig{
+		Config: &config.Config{
+			Hostname: container.TruncateID(info.Id),
+			Image:    info.ImageName,
+			Cmd:      strings.Fields(info.Command),
+		},
+		HostConfig: &config.HostConfig{
+			Binds:        info.Volumes,
+			Resources:    &config.Resources{},
+			PortBindings: info.PortMapping,
+		},
+		NetworkingConfig: &config.NetworkingConfig{},
+	}
+}
@@ -37,14 +37,18 @@ func NewStopCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	}
 
 	flags := cmd.Flags()
-	flags.IntVarP(&opts.timeout, "time", "t", 10, "Seconds to wait for stop before killing it")
+	flags.IntVarP(&opts.timeout, "time", "t", container.DefaultStopTimeout, "Seconds to wait for stop before killing it")
 	return cmd
 }
 
 func runStop(ctx context.Context, sudockerCli cmd.Cli, opts *stopOptions) error {
+	// 没有指定 --time 时使用每个容器自己的 stop timeout
+	var timeout *int
+	if opts.timeChanged {
+		timeout = &opts.timeout
+	}
 	errChan := parallelOperation(ctx, opts.containers, func(ctx context.Context, containerId string) error {
-
-		return container.StopContainer(containerId)
+		return releaseStale(container.StopContainer(containerId, timeout))
 	})
 	var errs []string
 	for _, ctr := range opts.containers {
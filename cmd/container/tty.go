@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/sirupsen/logrus"
 
 	"github.com/moby/sys/signal"
 )
@@ -44,23 +46,23 @@ func resizeTtyTo(ctx context.Context, id string, height, width uint, isExec bool
 	if height == 0 && width == 0 {
 		return nil
 	}
-
-	// options := container.ResizeOptions{
-	// 	Height: height,
-	// 	Width:  width,
-	// }
-
-	// var err error
-	// if isExec {
-	// 	err = apiClient.ContainerExecResize(ctx, id, options)
-	// } else {
-	// 	err = apiClient.ContainerResize(ctx, id, options)
-	// }
-
-	// if err != nil {
-	// 	logrus.Debugf("Error resize: %s\r", err)
-	// }
-	return nil
+	// exec 的进程以及前台运行的容器直接使用当前的终端，终端的大小由内核同步
+	if isExec {
+		return nil
+	}
+	info, err := container.GetInfoByContainerId(id)
+	if err != nil || !info.Monitored() {
+		return err
+	}
+	_, err = container.CallMonitor(ctx, info.Id, container.MonitorRequest{
+		Command: container.MonitorCommandResize,
+		Height:  height,
+		Width:   width,
+	})
+	if err != nil {
+		logrus.Debugf("Error resize: %s\r", err)
+	}
+	return err
 }
 
 // MonitorTtySize updates the container tty size when the terminal tty changes size
@@ -0,0 +1,52 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type unpauseOptions struct {
+	containers []string
+}
+
+func NewUnpauseCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	var opts unpauseOptions
+
+	return &cobra.Command{
+		Use:   "unpause CONTAINER [CONTAINER...]",
+		Short: "Unpause all processes within one or more containers",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.containers = args
+			return runUnpause(cmd.Context(), sudockerCli, &opts)
+		},
+		Annotations: map[string]string{
+			"aliases": "sudocker container unpause, sudocker unpause",
+		},
+	}
+}
+
+func runUnpause(ctx context.Context, sudockerCli cmd.Cli, opts *unpauseOptions) error {
+	errChan := parallelOperation(ctx, opts.containers, func(ctx context.Context, containerId string) error {
+		return container.UnpauseContainer(containerId)
+	})
+	var errs []string
+	for _, ctr := range opts.containers {
+		if err := <-errChan; err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		_, _ = fmt.Fprintln(sudockerCli.Out(), ctr)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
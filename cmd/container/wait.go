@@ -0,0 +1,57 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type waitOptions struct {
+	condition string
+
+	containers []string
+}
+
+func NewWaitCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	var opts waitOptions
+
+	cmd := &cobra.Command{
+		Use:   "wait [OPTIONS] CONTAINER [CONTAINER...]",
+		Short: "Block until one or more containers stop, then print their exit codes",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.containers = args
+			return runWait(cmd.Context(), sudockerCli, &opts)
+		},
+		Annotations: map[string]string{
+			"aliases": "sudocker container wait, sudocker wait",
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.condition, "condition", string(container.WaitConditionNotRunning),
+		`Condition to wait for ("`+string(container.WaitConditionNotRunning)+`"|"`+string(container.WaitConditionNextExit)+`"|"`+string(container.WaitConditionRemoved)+`")`)
+	return cmd
+}
+
+func runWait(ctx context.Context, sudockerCli cmd.Cli, opts *waitOptions) error {
+	var errs []string
+	for _, containerId := range opts.containers {
+		exitCode, err := container.WaitContainer(ctx, containerId, container.WaitCondition(opts.condition))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		_, _ = fmt.Fprintln(sudockerCli.Out(), exitCode)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
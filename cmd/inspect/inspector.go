@@ -0,0 +1,231 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cli/templates"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Inspector 定义了 inspect 输出对象的方式
+type Inspector interface {
+	Inspect(typedElement any, rawElement []byte) error
+	Flush() error
+}
+
+// TemplateInspector 使用Go模板输出对象
+type TemplateInspector struct {
+	outputStream io.Writer
+	buffer       *bytes.Buffer
+	tmpl         *template.Template
+}
+
+// NewTemplateInspector 创建一个输出到outputStream的TemplateInspector
+func NewTemplateInspector(outputStream io.Writer, tmpl *template.Template) Inspector {
+	return &TemplateInspector{
+		outputStream: outputStream,
+		buffer:       new(bytes.Buffer),
+		tmpl:         tmpl,
+	}
+}
+
+// NewTemplateInspectorFromString 解析模板并创建TemplateInspector，tmplStr为空时输出缩进的JSON
+func NewTemplateInspectorFromString(out io.Writer, tmplStr string) (Inspector, error) {
+	if tmplStr == "" {
+		return NewIndentedInspector(out), nil
+	}
+	if tmplStr == "json" {
+		return NewJSONInspector(out), nil
+	}
+
+	tmpl, err := templates.Parse(tmplStr)
+	if err != nil {
+		return nil, errors.Errorf("template parsing error: %s", err)
+	}
+	return NewTemplateInspector(out, tmpl), nil
+}
+
+// GetRefFunc 根据名称或ID获取对象，返回对象本身以及它的原始JSON
+type GetRefFunc func(ref string) (any, []byte, error)
+
+// Inspect 依次获取references对应的对象并输出，对象不存在时继续处理剩下的对象，最后返回错误
+func Inspect(out io.Writer, references []string, tmplStr string, getRef GetRefFunc) error {
+	inspector, err := NewTemplateInspectorFromString(out, tmplStr)
+	if err != nil {
+		return cli.StatusError{StatusCode: 64, Status: err.Error()}
+	}
+
+	var inspectErrs []string
+	for _, ref := range references {
+		element, raw, err := getRef(ref)
+		if err != nil {
+			inspectErrs = append(inspectErrs, err.Error())
+			continue
+		}
+
+		if err := inspector.Inspect(element, raw); err != nil {
+			inspectErrs = append(inspectErrs, err.Error())
+		}
+	}
+
+	if err := inspector.Flush(); err != nil {
+		logrus.Error(err)
+	}
+
+	if len(inspectErrs) != 0 {
+		return cli.StatusError{
+			StatusCode: 1,
+			Status:     strings.Join(inspectErrs, "\n"),
+		}
+	}
+	return nil
+}
+
+// Inspect 使用模板输出对象。模板中的字段在Go结构体上不存在时，退回到使用原始JSON解析出的map执行模板
+func (i *TemplateInspector) Inspect(typedElement any, rawElement []byte) error {
+	buffer := new(bytes.Buffer)
+	if err := i.tmpl.Execute(buffer, typedElement); err != nil {
+		if rawElement == nil {
+			return errors.Errorf("template parsing error: %v", err)
+		}
+		return i.tryRawInspectFallback(rawElement)
+	}
+	i.buffer.Write(buffer.Bytes())
+	i.buffer.WriteByte('\n')
+	return nil
+}
+
+// tryRawInspectFallback 使用原始JSON执行模板，数字按照原样保留而不是转换为float64
+func (i *TemplateInspector) tryRawInspectFallback(rawElement []byte) error {
+	var raw any
+	buffer := new(bytes.Buffer)
+	rdr := bytes.NewReader(rawElement)
+	dec := json.NewDecoder(rdr)
+	dec.UseNumber()
+
+	if rawErr := dec.Decode(&raw); rawErr != nil {
+		return errors.Errorf("unable to read inspect data: %v", rawErr)
+	}
+
+	tmplMissingKey := i.tmpl.Option("missingkey=error")
+	if rawErr := tmplMissingKey.Execute(buffer, raw); rawErr != nil {
+		return errors.Errorf("template parsing error: %v", rawErr)
+	}
+
+	i.buffer.Write(buffer.Bytes())
+	i.buffer.WriteByte('\n')
+	return nil
+}
+
+// Flush 将模板的输出写入outputStream
+func (i *TemplateInspector) Flush() error {
+	if i.buffer.Len() == 0 {
+		_, err := io.WriteString(i.outputStream, "\n")
+		return err
+	}
+	_, err := io.Copy(i.outputStream, i.buffer)
+	return err
+}
+
+// NewIndentedInspector 创建一个输出缩进JSON数组的Inspector
+func NewIndentedInspector(outputStream io.Writer) Inspector {
+	return &elementsInspector{
+		outputStream: outputStream,
+		raw: func(dst *bytes.Buffer, src []byte) error {
+			return json.Indent(dst, src, "", "    ")
+		},
+		el: func(v any) ([]byte, error) {
+			return json.MarshalIndent(v, "", "    ")
+		},
+	}
+}
+
+// NewJSONInspector 创建一个输出紧凑JSON数组的Inspector
+func NewJSONInspector(outputStream io.Writer) Inspector {
+	return &elementsInspector{
+		outputStream: outputStream,
+		raw:          json.Compact,
+		el:           json.Marshal,
+	}
+}
+
+type elementsInspector struct {
+	outputStream io.Writer
+	elements     []any
+	rawElements  [][]byte
+	raw          func(dst *bytes.Buffer, src []byte) error
+	el           func(v any) ([]byte, error)
+}
+
+func (e *elementsInspector) Inspect(typedElement any, rawElement []byte) error {
+	if rawElement != nil {
+		e.rawElements = append(e.rawElements, rawElement)
+	} else {
+		e.elements = append(e.elements, typedElement)
+	}
+	return nil
+}
+
+func (e *elementsInspector) Flush() error {
+	if len(e.elements) == 0 && len(e.rawElements) == 0 {
+		_, err := io.WriteString(e.outputStream, "[]\n")
+		return err
+	}
+
+	var buffer io.Reader
+	if len(e.rawElements) > 0 {
+		bytesBuffer := new(bytes.Buffer)
+		bytesBuffer.WriteString("[")
+		for idx, r := range e.rawElements {
+			bytesBuffer.Write(r)
+			if idx < len(e.rawElements)-1 {
+				bytesBuffer.WriteString(",")
+			}
+		}
+		bytesBuffer.WriteString("]")
+		output := new(bytes.Buffer)
+		if err := e.raw(output, bytesBuffer.Bytes()); err != nil {
+			return err
+		}
+		buffer = output
+	} else {
+		b, err := e.el(e.elements)
+		if err != nil {
+			return err
+		}
+		buffer = bytes.NewReader(b)
+	}
+
+	if _, err := io.Copy(e.outputStream, buffer); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.outputStream, "\n")
+	return err
+}
+
+type notFoundError struct {
+	kind string
+	ref  string
+}
+
+func (e notFoundError) Error() string {
+	return fmt.Sprintf("Error: No such %s: %s", e.kind, e.ref)
+}
+
+// NotFound 返回对象不存在时的错误
+func NotFound(kind, ref string) error {
+	return notFoundError{kind: kind, ref: ref}
+}
+
+// IsNotFound 判断错误是否表示对象不存在
+func IsNotFound(err error) bool {
+	var nf notFoundError
+	return errors.As(err, &nf)
+}
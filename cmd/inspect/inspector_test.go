@@ -0,0 +1,60 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type testElement struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func getTestRef(ref string) (any, []byte, error) {
+	if ref != "web" {
+		return nil, nil, NotFound("container", ref)
+	}
+	e := testElement{Name: "web", Status: "running"}
+	raw, err := json.Marshal(e)
+	return e, raw, err
+}
+
+func TestInspectTemplate(t *testing.T) {
+	out := new(bytes.Buffer)
+	if err := Inspect(out, []string{"web"}, "{{.Name}} {{upper .Status}}", getTestRef); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "web RUNNING\n" {
+		t.Errorf("expected %q but %q got", "web RUNNING\n", out.String())
+	}
+
+	// 结构体上不存在的字段退回到使用原始JSON
+	out.Reset()
+	if err := Inspect(out, []string{"web"}, "{{.status}}", getTestRef); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "running\n" {
+		t.Errorf("expected %q but %q got", "running\n", out.String())
+	}
+}
+
+func TestInspectNotFound(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := Inspect(out, []string{"web", "db"}, "", getTestRef)
+	if err == nil {
+		t.Fatal("expected an error for the missing reference")
+	}
+	var elements []testElement
+	if err := json.Unmarshal(out.Bytes(), &elements); err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 1 || elements[0].Name != "web" {
+		t.Errorf("unexpected output %s", out.String())
+	}
+	if !IsNotFound(errors.WithMessage(NotFound("container", "db"), "inspect")) {
+		t.Error("expected a wrapped NotFound error to be detected")
+	}
+}
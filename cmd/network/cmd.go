@@ -19,6 +19,7 @@ func NewNetworkCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
 	cmd.AddCommand(
 		NewCreateCommand(sudockerCli),
 		NewListCommand(sudockerCli),
+		NewInspectCommand(sudockerCli),
 		NewRemoveCommand(sudockerCli),
 	)
 	return cmd
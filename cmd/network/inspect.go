@@ -0,0 +1,56 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/cmd/inspect"
+	"github.com/DeJeune/sudocker/runtime/pkg/network"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type inspectOptions struct {
+	format string
+	names  []string
+}
+
+func NewInspectCommand(sudockerCli cmd.Cli) *cobra.Command {
+	var opts inspectOptions
+
+	cmd := &cobra.Command{
+		Use:   "inspect [OPTIONS] NETWORK [NETWORK...]",
+		Short: "Display detailed information on one or more networks",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.names = args
+			return runInspect(cmd.Context(), sudockerCli, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "", "Format output using a custom template:\n'json':             Print in JSON format\n'TEMPLATE':         Print output using the given Go template.")
+	return cmd
+}
+
+func runInspect(ctx context.Context, sudockerCli cmd.Cli, opts inspectOptions) error {
+	return inspect.Inspect(sudockerCli.Out(), opts.names, opts.format, GetNetworkRef)
+}
+
+// GetNetworkRef 是 inspect 获取网络信息的 GetRefFunc
+func GetNetworkRef(name string) (any, []byte, error) {
+	net, err := network.GetNetwork(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, inspect.NotFound("network", name)
+		}
+		return nil, nil, err
+	}
+	raw, err := json.Marshal(net)
+	if err != nil {
+		return nil, nil, err
+	}
+	return net, raw, nil
+}
@@ -0,0 +1,24 @@
+package system
+
+import (
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/spf13/cobra"
+)
+
+// NewSystemCommand 创建 system 命令组
+func NewSystemCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "system",
+		Short: "Manage Sudocker",
+		Args:  cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			return nil
+		},
+	}
+	cmd.AddCommand(
+		NewReconcileCommand(sudockerCli),
+	)
+	return cmd
+}
@@ -0,0 +1,74 @@
+package system
+
+import (
+	"context"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/cmd/container"
+	"github.com/DeJeune/sudocker/cmd/inspect"
+	"github.com/DeJeune/sudocker/cmd/network"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	typeContainer = "container"
+	typeNetwork   = "network"
+)
+
+type inspectOptions struct {
+	inspectType string
+	format      string
+	ids         []string
+}
+
+// NewInspectCommand 创建顶层的 inspect 命令，可以查看容器和网络
+func NewInspectCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	var opts inspectOptions
+
+	cmd := &cobra.Command{
+		Use:   "inspect [OPTIONS] NAME|ID [NAME|ID...]",
+		Short: "Return low-level information on Sudocker objects",
+		Args:  cli.RequiresMinArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.ids = args
+			return runInspect(cmd.Context(), sudockerCli, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.format, "format", "f", "", "Format output using a custom template:\n'json':             Print in JSON format\n'TEMPLATE':         Print output using the given Go template.")
+	flags.StringVar(&opts.inspectType, "type", "", "Return JSON for specified type")
+	return cmd
+}
+
+func runInspect(ctx context.Context, sudockerCli cmd.Cli, opts inspectOptions) error {
+	var getRef inspect.GetRefFunc
+	switch opts.inspectType {
+	case typeContainer:
+		getRef = container.GetContainerRef
+	case typeNetwork:
+		getRef = network.GetNetworkRef
+	case "":
+		getRef = inspectAll
+	default:
+		return errors.Errorf("%q is not a valid value for --type", opts.inspectType)
+	}
+	return inspect.Inspect(sudockerCli.Out(), opts.ids, opts.format, getRef)
+}
+
+// inspectAll 依次按照容器、网络查找对象，返回第一个找到的对象。
+// 容器和网络重名时需要使用 --type 指定类型
+func inspectAll(ref string) (any, []byte, error) {
+	for _, getRef := range []inspect.GetRefFunc{container.GetContainerRef, network.GetNetworkRef} {
+		v, raw, err := getRef(ref)
+		if err == nil {
+			return v, raw, nil
+		}
+		if !inspect.IsNotFound(err) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, inspect.NotFound("object", ref)
+}
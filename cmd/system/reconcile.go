@@ -0,0 +1,33 @@
+package system
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DeJeune/sudocker/cli"
+	"github.com/DeJeune/sudocker/cmd"
+	"github.com/DeJeune/sudocker/cmd/container"
+	runtimecontainer "github.com/DeJeune/sudocker/runtime/pkg/container"
+	"github.com/spf13/cobra"
+)
+
+// NewReconcileCommand 创建 system reconcile 命令，修正init进程已经不存在的容器记录
+func NewReconcileCommand(sudockerCli *cmd.SudockerCli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Mark containers whose process is gone as stopped and release their resources",
+		Args:  cli.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReconcile(cmd.Context(), sudockerCli)
+		},
+	}
+	return cmd
+}
+
+func runReconcile(ctx context.Context, sudockerCli cmd.Cli) error {
+	reconciled, err := container.ReconcileContainers()
+	for _, info := range reconciled {
+		_, _ = fmt.Fprintln(sudockerCli.Out(), runtimecontainer.TruncateID(info.Id))
+	}
+	return err
+}
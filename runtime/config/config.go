@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 
+	"github.com/docker/go-units"
 	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
@@ -61,9 +62,17 @@ type Capabilities struct {
 	Ambient []string
 }
 
+// Rlimit 是容器进程的资源限制，Type 为 RLIMIT_* 常量
+type Rlimit struct {
+	Type int    `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
 type Config struct {
 	Hostname     string
 	Domainname   string
+	User         string // User that will run the command(s) inside the container, also support user:group
 	OpenStdin    bool
 	StdinOnce    bool
 	AttachStdin  bool
@@ -73,16 +82,27 @@ type Config struct {
 	Cmd          []string
 	Image        string
 	Env          []string
+	WorkingDir   string            // Current directory (PWD) in the command will be launched
+	Labels       map[string]string `json:",omitempty"` // List of labels set to this container
+	StopSignal   string            `json:",omitempty"` // Signal to stop a container
+	StopTimeout  *int              `json:",omitempty"` // Timeout (in seconds) to stop a container
 }
 
 type NetworkMode string
 
 type HostConfig struct {
-	Binds []string // List of volume bindings for this container
+	Binds       []string // List of volume bindings for this container
+	GroupAdd    []string // List of additional groups that the container process will run as
+	CapAdd      []string // List of kernel capabilities to add to the container
+	CapDrop     []string // List of kernel capabilities to remove from the container
+	Privileged  bool     // Is the container in privileged mode
+	SecurityOpt []string // List of security options, a seccomp profile file is replaced by its content
 	*Resources
-	AutoRemove   bool
-	NetworkMode  NetworkMode
-	PortBindings []string
+	AutoRemove    bool
+	NetworkMode   NetworkMode
+	PortBindings  []string
+	RestartPolicy RestartPolicy
+	Ulimits       []*units.Ulimit
 }
 
 // IDMap represents UID/GID Mappings for User Namespaces.
@@ -4,14 +4,17 @@ package config
 // for the exec feature of docker.
 type ExecOptions struct {
 	User         string   // User that will run the command
+	GroupAdd     []string // Additional groups that the command will run as
+	CapAdd       []string // Kernel capabilities to add to the command
+	CapDrop      []string // Kernel capabilities to remove from the command
 	Privileged   bool     // Is the container in privileged mode
+	SecurityOpt  []string // Security options of the command, on top of the container's
 	Tty          bool     // Attach standard streams to a tty.
 	ConsoleSize  *[2]uint `json:",omitempty"` // Initial console size [height, width]
 	AttachStdin  bool     // Attach the standard input, makes possible user interaction
 	AttachStderr bool     // Attach the standard error
 	AttachStdout bool     // Attach the standard output
 	Detach       bool     // Execute in detach mode
-	DetachKeys   string   // Escape keys for detach
 	Env          []string // Environment variables
 	WorkingDir   string   // Working directory
 	Cmd          []string // Execution commands and args
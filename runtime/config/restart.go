@@ -0,0 +1,63 @@
+package config
+
+import "fmt"
+
+// RestartPolicyMode 是容器的重启策略
+type RestartPolicyMode string
+
+const (
+	RestartPolicyDisabled      RestartPolicyMode = "no"
+	RestartPolicyAlways        RestartPolicyMode = "always"
+	RestartPolicyOnFailure     RestartPolicyMode = "on-failure"
+	RestartPolicyUnlessStopped RestartPolicyMode = "unless-stopped"
+)
+
+// RestartPolicy 描述容器退出后是否以及如何被重启。
+// MaximumRetryCount 只对 on-failure 有效，为0时表示不限制重试次数
+type RestartPolicy struct {
+	Name              RestartPolicyMode
+	MaximumRetryCount int
+}
+
+// IsNone 判断容器是否没有设置重启策略
+func (rp *RestartPolicy) IsNone() bool {
+	return rp.Name == RestartPolicyDisabled || rp.Name == ""
+}
+
+// ShouldRestart 根据容器的退出码、已重启次数以及是否被用户手动停止，判断容器是否需要重启。
+// 被 stop 手动停止的容器不会被重启
+func (rp *RestartPolicy) ShouldRestart(exitCode, restartCount int, manuallyStopped bool) bool {
+	if manuallyStopped {
+		return false
+	}
+	switch rp.Name {
+	case RestartPolicyAlways, RestartPolicyUnlessStopped:
+		return true
+	case RestartPolicyOnFailure:
+		if exitCode == 0 {
+			return false
+		}
+		return rp.MaximumRetryCount == 0 || restartCount < rp.MaximumRetryCount
+	default:
+		return false
+	}
+}
+
+// ValidateRestartPolicy 校验重启策略是否合法
+func ValidateRestartPolicy(policy RestartPolicy) error {
+	switch policy.Name {
+	case RestartPolicyAlways, RestartPolicyUnlessStopped, RestartPolicyDisabled, "":
+		if policy.MaximumRetryCount != 0 {
+			return fmt.Errorf("invalid restart policy: maximum retry count can only be used with 'on-failure'")
+		}
+		return nil
+	case RestartPolicyOnFailure:
+		if policy.MaximumRetryCount < 0 {
+			return fmt.Errorf("invalid restart policy: maximum retry count cannot be negative")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid restart policy: unknown policy '%s'; use one of '%s', '%s', '%s', or '%s'",
+			policy.Name, RestartPolicyDisabled, RestartPolicyAlways, RestartPolicyOnFailure, RestartPolicyUnlessStopped)
+	}
+}
@@ -62,26 +62,39 @@ func New(capConfig *config.Capabilities) (*Caps, error) {
 		c   Caps
 	)
 
-	unknownCaps := make(map[string]struct{})
-	c.caps = map[capability.CapType][]capability.Cap{
-		capability.BOUNDING:    capSlice(capConfig.Bounding, unknownCaps),
-		capability.EFFECTIVE:   capSlice(capConfig.Effective, unknownCaps),
-		capability.INHERITABLE: capSlice(capConfig.Inheritable, unknownCaps),
-		capability.PERMITTED:   capSlice(capConfig.Permitted, unknownCaps),
-		capability.AMBIENT:     capSlice(capConfig.Ambient, unknownCaps),
-	}
 	if c.pid, err = capability.NewPid2(0); err != nil {
 		return nil, err
 	}
 	if err = c.pid.Load(); err != nil {
 		return nil, err
 	}
+	unknownCaps := make(map[string]struct{})
+	c.caps = map[capability.CapType][]capability.Cap{
+		capability.BOUNDING:    c.available(capSlice(capConfig.Bounding, unknownCaps), unknownCaps),
+		capability.EFFECTIVE:   c.available(capSlice(capConfig.Effective, unknownCaps), unknownCaps),
+		capability.INHERITABLE: c.available(capSlice(capConfig.Inheritable, unknownCaps), unknownCaps),
+		capability.PERMITTED:   c.available(capSlice(capConfig.Permitted, unknownCaps), unknownCaps),
+		capability.AMBIENT:     c.available(capSlice(capConfig.Ambient, unknownCaps), unknownCaps),
+	}
 	if len(unknownCaps) > 0 {
 		logrus.Warn("ignoring unknown or unavailable capabilities: ", mapKeys(unknownCaps))
 	}
 	return &c, nil
 }
 
+// available 去掉当前进程的bounding set中没有的capabilities，这些capabilities无法再授予容器进程
+func (c *Caps) available(caps []capability.Cap, unavailableCaps map[string]struct{}) []capability.Cap {
+	var out []capability.Cap
+	for _, v := range caps {
+		if c.pid.Get(capability.BOUNDING, v) {
+			out = append(out, v)
+		} else {
+			unavailableCaps["CAP_"+strings.ToUpper(v.String())] = struct{}{}
+		}
+	}
+	return out
+}
+
 type Caps struct {
 	pid  capability.Capabilities
 	caps map[capability.CapType][]capability.Cap
@@ -0,0 +1,125 @@
+package capabilites
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/pkg/errors"
+)
+
+// allCapabilities 表示 --cap-add 或 --cap-drop 中的全部capabilities
+const allCapabilities = "ALL"
+
+// defaultCapabilities 是非特权容器默认拥有的capabilities，与 docker 保持一致
+var defaultCapabilities = []string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_FSETID",
+	"CAP_FOWNER",
+	"CAP_MKNOD",
+	"CAP_NET_RAW",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETFCAP",
+	"CAP_SETPCAP",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_SYS_CHROOT",
+	"CAP_KILL",
+	"CAP_AUDIT_WRITE",
+}
+
+// DefaultCapabilities 返回容器默认拥有的capabilities
+func DefaultCapabilities() []string {
+	return append([]string(nil), defaultCapabilities...)
+}
+
+// AllCapabilities 返回当前内核支持的所有capabilities
+func AllCapabilities() []string {
+	caps := make([]string, 0, len(capabilityMap))
+	for name := range capabilityMap {
+		caps = append(caps, name)
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+// NormalizeCapabilities 将 net_admin、NET_ADMIN 等写法统一为 CAP_NET_ADMIN，ALL 保持不变，未知的capability返回错误
+func NormalizeCapabilities(caps []string) ([]string, error) {
+	normalized := make([]string, 0, len(caps))
+	for _, c := range caps {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if c != allCapabilities && !strings.HasPrefix(c, "CAP_") {
+			c = "CAP_" + c
+		}
+		if _, ok := capabilityMap[c]; !ok && c != allCapabilities {
+			return nil, errors.Errorf("unknown capability: %q", c)
+		}
+		normalized = append(normalized, c)
+	}
+	return normalized, nil
+}
+
+// TweakCapabilities 在base的基础上依次去掉drop、加上add中的capabilities，同时出现在两者中时以add为准。
+// add 中包含 ALL 时为除drop以外的全部capabilities，drop 中包含 ALL 时只保留add中的capabilities
+func TweakCapabilities(base, add, drop []string) ([]string, error) {
+	add, err := NormalizeCapabilities(add)
+	if err != nil {
+		return nil, err
+	}
+	drop, err = NormalizeCapabilities(drop)
+	if err != nil {
+		return nil, err
+	}
+	caps := make(map[string]struct{})
+	switch {
+	case inSlice(add, allCapabilities):
+		for _, c := range AllCapabilities() {
+			if !inSlice(drop, c) {
+				caps[c] = struct{}{}
+			}
+		}
+	case inSlice(drop, allCapabilities):
+	default:
+		for _, c := range base {
+			if !inSlice(drop, c) {
+				caps[c] = struct{}{}
+			}
+		}
+	}
+	for _, c := range add {
+		if c != allCapabilities {
+			caps[c] = struct{}{}
+		}
+	}
+	return mapKeys(caps), nil
+}
+
+// NewConfig 返回容器进程的capabilities配置：bounding、effective、permitted 都为caps，不设置 inheritable 和 ambient
+func NewConfig(caps []string) *config.Capabilities {
+	return &config.Capabilities{
+		Bounding:  append([]string(nil), caps...),
+		Effective: append([]string(nil), caps...),
+		Permitted: append([]string(nil), caps...),
+	}
+}
+
+// Mask 返回caps对应的位掩码，第n位对应编号为n的capability，未知的capability被忽略
+func Mask(caps []string) uint64 {
+	var mask uint64
+	for _, c := range caps {
+		if v, ok := capabilityMap[c]; ok {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask
+}
+
+func inSlice(slice []string, s string) bool {
+	for _, ss := range slice {
+		if ss == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package capabilites
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTweakCapabilities(t *testing.T) {
+	base := []string{"CAP_CHOWN", "CAP_KILL", "CAP_NET_RAW"}
+	tests := []struct {
+		name     string
+		add      []string
+		drop     []string
+		expected []string
+	}{
+		{name: "default", expected: []string{"CAP_CHOWN", "CAP_KILL", "CAP_NET_RAW"}},
+		{name: "add and drop", add: []string{"net_admin"}, drop: []string{"NET_RAW"}, expected: []string{"CAP_CHOWN", "CAP_KILL", "CAP_NET_ADMIN"}},
+		{name: "add wins", add: []string{"CAP_KILL"}, drop: []string{"kill"}, expected: []string{"CAP_CHOWN", "CAP_KILL", "CAP_NET_RAW"}},
+		{name: "drop all", add: []string{"SYS_ADMIN"}, drop: []string{"ALL"}, expected: []string{"CAP_SYS_ADMIN"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps, err := TweakCapabilities(base, tt.add, tt.drop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(caps, tt.expected) {
+				t.Errorf("expected %v but %v got", tt.expected, caps)
+			}
+		})
+	}
+
+	caps, err := TweakCapabilities(base, []string{"ALL"}, []string{"CHOWN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caps) != len(AllCapabilities())-1 || inSlice(caps, "CAP_CHOWN") {
+		t.Errorf("expected all capabilities except CAP_CHOWN but %v got", caps)
+	}
+	if _, err := TweakCapabilities(base, []string{"BOGUS"}, nil); err == nil {
+		t.Error("expected error for unknown capability")
+	}
+}
@@ -0,0 +1,104 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/cgroups"
+	"golang.org/x/sys/unix"
+)
+
+type FreezerSubsystem struct{}
+
+func (s *FreezerSubsystem) Name() string {
+	return "freezer"
+}
+
+func (s *FreezerSubsystem) Apply(path string, _ *config.Resources, pid int) error {
+	return apply(path, pid)
+}
+
+// Set freezer 没有资源限制，冻结和解冻通过 SetState 完成
+func (s *FreezerSubsystem) Set(_ string, _ *config.Resources) error {
+	return nil
+}
+
+// SetState 将cgroup设置为冻结或解冻状态，并等待状态生效
+func (s *FreezerSubsystem) SetState(path string, state config.FreezerState) error {
+	switch state {
+	case config.Frozen, config.Thawed:
+	case config.Undefined:
+		return nil
+	default:
+		return fmt.Errorf("invalid freezer state %q", state)
+	}
+
+	// 冻结过程中有进程正在fork时，内核可能会停留在FREEZING状态，需要重新写入FROZEN
+	for i := 0; i < 1000; i++ {
+		if i%50 == 49 && state == config.Frozen {
+			// 长时间无法冻结时，先解冻一下再重试
+			_ = cgroups.WriteFile(path, "freezer.state", string(config.Thawed))
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := cgroups.WriteFile(path, "freezer.state", string(state)); err != nil {
+			return err
+		}
+		current, err := cgroups.ReadFile(path, "freezer.state")
+		if err != nil {
+			return err
+		}
+		switch strings.TrimSpace(current) {
+		case "FREEZING":
+			time.Sleep(time.Millisecond)
+			continue
+		case string(state):
+			return nil
+		default:
+			return fmt.Errorf("unexpected freezer state %q after setting %q", strings.TrimSpace(current), state)
+		}
+	}
+	// 冻结失败时解冻，避免容器停留在半冻结的状态
+	_ = cgroups.WriteFile(path, "freezer.state", string(config.Thawed))
+	return errors.New("unable to freeze")
+}
+
+// GetState 返回cgroup当前的冻结状态
+func (s *FreezerSubsystem) GetState(path string) (config.FreezerState, error) {
+	for {
+		state, err := cgroups.ReadFile(path, "freezer.state")
+		if err != nil {
+			// freezer子系统不存在或cgroup已经被删除，认为没有被冻结
+			if os.IsNotExist(err) || errors.Is(err, unix.ENODEV) {
+				err = nil
+			}
+			return config.Undefined, err
+		}
+		switch strings.TrimSpace(state) {
+		case "THAWED":
+			return config.Thawed, nil
+		case "FROZEN":
+			// 父cgroup被冻结时，子cgroup也显示为FROZEN，这里只关心自身是否被冻结
+			selfState, err := cgroups.ReadFile(path, "freezer.self_freezing")
+			if err != nil {
+				if os.IsNotExist(err) {
+					return config.Frozen, nil
+				}
+				return config.Undefined, err
+			}
+			if strings.TrimSpace(selfState) == "1" {
+				return config.Frozen, nil
+			}
+			return config.Thawed, nil
+		case "FREEZING":
+			// 正在冻结中，稍后再读取
+			time.Sleep(time.Millisecond)
+			continue
+		default:
+			return config.Undefined, fmt.Errorf("unknown freezer.state %q", state)
+		}
+	}
+}
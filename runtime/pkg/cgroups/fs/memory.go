@@ -2,10 +2,12 @@ package fs
 
 import (
 	"errors"
+	"os"
 	"strconv"
 
 	"github.com/DeJeune/sudocker/runtime/config"
 	"github.com/DeJeune/sudocker/runtime/pkg/cgroups"
+	"github.com/DeJeune/sudocker/runtime/pkg/cgroups/fscommon"
 	"golang.org/x/sys/unix"
 )
 
@@ -64,3 +66,16 @@ func (s *MemorySubsystem) Set(path string, r *config.Resources) error {
 	}
 	return nil
 }
+
+// OOMKillCount 返回cgroup中被OOM killer杀死的进程数，需要4.13以上的内核
+func (m *Manager) OOMKillCount() (uint64, error) {
+	path := m.Path("memory")
+	if path == "" {
+		return 0, nil
+	}
+	c, err := fscommon.GetValueByKey(path, "memory.oom_control", "oom_kill")
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	return c, err
+}
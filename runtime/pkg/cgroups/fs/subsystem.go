@@ -36,6 +36,7 @@ var Subsystems = []Subsystem{
 	&CpusetSubsystem{},
 	&MemorySubsystem{},
 	&CpuSubsystem{},
+	&FreezerSubsystem{},
 }
 
 func NewManager(cg *config.Cgroup, paths map[string]string) (*Manager, error) {
@@ -134,6 +135,47 @@ func (m *Manager) Set(r *config.Resources) error {
 	return nil
 }
 
+// GetPids 返回cgroup中的所有进程。进程会被加入到每一个子系统中，因此读取任意一个子系统即可
+func (m *Manager) GetPids() ([]int, error) {
+	return cgroups.GetPids(m.procsPath())
+}
+
+// GetAllPids 返回cgroup及其所有子cgroup中的进程
+func (m *Manager) GetAllPids() ([]int, error) {
+	return cgroups.GetAllPids(m.procsPath())
+}
+
+func (m *Manager) procsPath() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sys := range Subsystems {
+		if p, ok := m.paths[sys.Name()]; ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// Freeze 冻结或解冻cgroup中的所有进程
+func (m *Manager) Freeze(state config.FreezerState) error {
+	path := m.Path("freezer")
+	if path == "" {
+		return errors.New("cannot toggle freezer: cgroups not configured for container")
+	}
+	freezer := &FreezerSubsystem{}
+	return freezer.SetState(path, state)
+}
+
+// GetFreezerState 返回cgroup当前的冻结状态
+func (m *Manager) GetFreezerState() (config.FreezerState, error) {
+	path := m.Path("freezer")
+	if path == "" {
+		return config.Undefined, nil
+	}
+	freezer := &FreezerSubsystem{}
+	return freezer.GetState(path)
+}
+
 func (m *Manager) GetCgroups() (*config.Cgroup, error) {
 	return m.cgroups, nil
 }
@@ -0,0 +1,129 @@
+package fs2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/cgroups"
+	"golang.org/x/sys/unix"
+)
+
+// setFreezer 通过cgroup.freeze冻结或解冻cgroup，并等待cgroup.events中的frozen状态生效
+func setFreezer(dirPath string, state config.FreezerState) error {
+	var stateStr string
+	switch state {
+	case config.Undefined:
+		return nil
+	case config.Frozen:
+		stateStr = "1"
+	case config.Thawed:
+		stateStr = "0"
+	default:
+		return fmt.Errorf("invalid freezer state %q", state)
+	}
+
+	fd, err := cgroups.OpenFile(dirPath, "cgroup.freeze", unix.O_RDWR)
+	if err != nil {
+		// 内核不支持cgroup v2 freezer（5.2之前）时，解冻可以认为总是成功的
+		if state != config.Frozen {
+			return nil
+		}
+		return fmt.Errorf("freezer not supported: %w", err)
+	}
+	defer fd.Close()
+
+	if _, err := fd.WriteString(stateStr); err != nil {
+		return err
+	}
+	if actual, err := readFreezer(dirPath, fd); err != nil {
+		return err
+	} else if actual != state {
+		return fmt.Errorf(`expected "cgroup.freeze" to be in state %q but was in %q`, state, actual)
+	}
+	return nil
+}
+
+// getFreezer 返回cgroup当前的冻结状态
+func getFreezer(dirPath string) (config.FreezerState, error) {
+	fd, err := cgroups.OpenFile(dirPath, "cgroup.freeze", unix.O_RDONLY)
+	if err != nil {
+		// cgroup已经被删除或者内核不支持freezer，认为没有被冻结
+		if os.IsNotExist(err) || errors.Is(err, unix.ENODEV) {
+			err = nil
+		}
+		return config.Undefined, err
+	}
+	defer fd.Close()
+
+	return readFreezer(dirPath, fd)
+}
+
+func readFreezer(dirPath string, fd *os.File) (config.FreezerState, error) {
+	if _, err := fd.Seek(0, 0); err != nil {
+		return config.Undefined, err
+	}
+	state := make([]byte, 2)
+	if _, err := fd.Read(state); err != nil {
+		return config.Undefined, err
+	}
+	switch string(state) {
+	case "0\n":
+		return config.Thawed, nil
+	case "1\n":
+		return waitFrozen(dirPath)
+	default:
+		return config.Undefined, fmt.Errorf(`unknown "cgroup.freeze" state: %q`, state)
+	}
+}
+
+// waitFrozen 等待cgroup.events中的frozen变为1，即cgroup中的所有进程都已经被冻结
+func waitFrozen(dirPath string) (config.FreezerState, error) {
+	const (
+		// 总共最多等待1秒
+		waitTime = 10 * time.Millisecond
+		maxIter  = 100
+	)
+	fd, err := cgroups.OpenFile(dirPath, "cgroup.events", unix.O_RDONLY)
+	if err != nil {
+		return config.Undefined, err
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	for i := 0; scanner.Scan(); {
+		if i == maxIter {
+			return config.Undefined, fmt.Errorf("timeout of %s reached waiting for the cgroup to freeze", waitTime*maxIter)
+		}
+		if val, ok := strings.CutPrefix(scanner.Text(), "frozen "); ok {
+			if val[0] == '1' {
+				return config.Frozen, nil
+			}
+			i++
+			// 还没有冻结完成，稍后重新读取
+			time.Sleep(waitTime)
+			if _, err := fd.Seek(0, 0); err != nil {
+				return config.Undefined, err
+			}
+			scanner = bufio.NewScanner(fd)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return config.Undefined, err
+	}
+	return config.Undefined, errors.New(`"frozen" not found in "cgroup.events"`)
+}
+
+// Freeze 冻结或解冻cgroup中的所有进程
+func (m *BaseManager) Freeze(state config.FreezerState) error {
+	return setFreezer(m.dirPath, state)
+}
+
+// GetFreezerState 返回cgroup当前的冻结状态
+func (m *BaseManager) GetFreezerState() (config.FreezerState, error) {
+	return getFreezer(m.dirPath)
+}
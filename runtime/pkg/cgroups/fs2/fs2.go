@@ -1,7 +1,9 @@
 package fs2
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/DeJeune/sudocker/runtime/config"
@@ -87,3 +89,59 @@ func CheckMemoryUsage(dirPath string, r *config.Resources) error {
 
 	return nil
 }
+
+// Apply 创建cgroup并将pid加入其中，pid为-1时只创建cgroup
+func (m *BaseManager) Apply(pid int) error {
+	if err := CreateCgroupPath(m.dirPath, m.config); err != nil {
+		return err
+	}
+	return cgroups.WriteCgroupProc(m.dirPath, pid)
+}
+
+// Set 设置cgroup的资源限制，cgroup v1 的cpu shares被转换为 v2 的cpu weight
+func (m *BaseManager) Set(r *config.Resources) error {
+	if r == nil {
+		return nil
+	}
+	res := *r
+	if res.CpuWeight == 0 && res.CpuShares != 0 {
+		res.CpuWeight = cgroups.ConvertCPUSharesToCgroupV2Value(res.CpuShares)
+	}
+	for _, set := range []func(string, *config.Resources) error{
+		setPids, setMemory, setIo, setCpu, SetCpuset, setHugeTlb,
+	} {
+		if err := set(m.dirPath, &res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Destroy 删除cgroup及其子cgroup
+func (m *BaseManager) Destroy() error {
+	return cgroups.RemovePath(m.dirPath)
+}
+
+// Path 返回cgroup的路径，cgroup v2 只有一个层级，因此忽略子系统名称
+func (m *BaseManager) Path(_ string) string {
+	return m.dirPath
+}
+
+// GetPids 返回cgroup中的所有进程
+func (m *BaseManager) GetPids() ([]int, error) {
+	return cgroups.GetPids(m.dirPath)
+}
+
+// GetAllPids 返回cgroup及其所有子cgroup中的进程
+func (m *BaseManager) GetAllPids() ([]int, error) {
+	return cgroups.GetAllPids(m.dirPath)
+}
+
+// OOMKillCount 返回cgroup中被OOM killer杀死的进程数
+func (m *BaseManager) OOMKillCount() (uint64, error) {
+	c, err := fscommon.GetValueByKey(m.dirPath, "memory.events", "oom_kill")
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	return c, err
+}
@@ -46,3 +46,26 @@ func GetCgroupParamString(path, file string) (string, error) {
 
 	return strings.TrimSpace(contents), nil
 }
+
+// GetValueByKey 读取 "key value" 格式的cgroup文件（如memory.stat），返回key对应的值，key不存在时返回0
+func GetValueByKey(path, file, key string) (uint64, error) {
+	content, err := cgroups.ReadFile(path, file)
+	if err != nil {
+		return 0, err
+	}
+
+	key += " "
+	lines := strings.Split(content, "\n")
+	for _, line := range lines {
+		v, ok := strings.CutPrefix(line, key)
+		if ok {
+			val, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				err = &ParseError{Path: path, File: file, Err: err}
+			}
+			return val, err
+		}
+	}
+
+	return 0, nil
+}
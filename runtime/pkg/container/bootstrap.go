@@ -0,0 +1,198 @@
+package container
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/capabilites"
+	"github.com/DeJeune/sudocker/runtime/pkg/devices"
+	"github.com/docker/go-units"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// BootstrapVersion 是引导消息的格式版本，init进程拒绝不认识的版本
+	BootstrapVersion = 1
+	// defaultPathEnv 是容器的环境变量中没有PATH时使用的默认值
+	defaultPathEnv = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	// defaultTermEnv 是分配了终端时TERM的默认值
+	defaultTermEnv = "TERM=xterm"
+)
+
+// Bootstrap 是父进程通过管道发送给容器init进程的引导消息，init进程按照它配置容器后执行用户命令
+type Bootstrap struct {
+	Version int `json:"version"`
+	// Args 是用户命令及其参数，Args[0] 在容器的PATH中查找
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+	Cwd  string   `json:"cwd"`
+	// User 是 --user 的原始值，在容器的 /etc/passwd 和 /etc/group 中解析
+	User             string   `json:"user,omitempty"`
+	AdditionalGroups []string `json:"additional_groups,omitempty"`
+	Hostname         string   `json:"hostname,omitempty"`
+	Domainname       string   `json:"domainname,omitempty"`
+	// Capabilities 在切换用户前后设置，见 RunContainerInitProcess
+	Capabilities *config.Capabilities `json:"capabilities"`
+	// Rlimits 在执行用户命令之前设置
+	Rlimits []config.Rlimit `json:"rlimits,omitempty"`
+	// Mounts 按顺序挂载，Destination 是容器内的路径。bind mount的Source是宿主机上的路径，
+	// 在pivot_root之前挂载，其余的在pivot_root之后挂载
+	Mounts []*config.Mount `json:"mounts,omitempty"`
+	// Devices 在挂载完成后创建在容器的 /dev 中
+	Devices []*devices.Device `json:"devices,omitempty"`
+	// MaskedPaths 和 ReadonlyPaths 在创建设备文件之后处理，特权容器没有这两项
+	MaskedPaths   []string `json:"masked_paths,omitempty"`
+	ReadonlyPaths []string `json:"readonly_paths,omitempty"`
+	// Seccomp 是编译好的seccomp过滤器，为空时不过滤系统调用
+	Seccomp []unix.SockFilter `json:"seccomp,omitempty"`
+	// NoNewPrivileges 决定是否设置 PR_SET_NO_NEW_PRIVS，以及seccomp过滤器的加载时机，见 RunContainerInitProcess
+	NoNewPrivileges bool `json:"no_new_privileges,omitempty"`
+}
+
+// NewBootstrap 根据容器的配置生成init进程的引导消息
+// 特权容器拥有所有的capabilities和宿主机的设备，/sys 和 /proc 可写；
+// 非特权容器使用默认的capabilities和设备，并屏蔽 /proc 和 /sys 中的敏感路径
+func NewBootstrap(cfg *config.Config, hostConfig *config.HostConfig) (*Bootstrap, error) {
+	caps, err := containerCapabilities(hostConfig)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bootstrap{
+		Version:          BootstrapVersion,
+		Args:             cfg.Cmd,
+		Env:              MergeEnv(DefaultEnv(cfg), cfg.Env),
+		Cwd:              workingDir(cfg.WorkingDir),
+		User:             cfg.User,
+		AdditionalGroups: hostConfig.GroupAdd,
+		Hostname:         cfg.Hostname,
+		Domainname:       cfg.Domainname,
+		Capabilities:     capabilites.NewConfig(caps),
+		Mounts:           defaultMounts(hostConfig.Privileged),
+	}
+	security, err := ParseSecurityOpts(hostConfig.SecurityOpt)
+	if err != nil {
+		return nil, err
+	}
+	if b.Rlimits, err = containerRlimits(hostConfig.Ulimits); err != nil {
+		return nil, err
+	}
+	b.NoNewPrivileges = security.NoNewPrivileges
+	if b.Seccomp, err = security.SeccompFilter(hostConfig.Privileged, caps); err != nil {
+		return nil, err
+	}
+	if !hostConfig.Privileged {
+		b.Devices = defaultDevices
+		b.MaskedPaths = defaultMaskedPaths
+		b.ReadonlyPaths = defaultReadonlyPaths
+	}
+	return b, nil
+}
+
+// containerCapabilities 返回容器进程的capabilities，特权容器忽略 --cap-add 和 --cap-drop
+func containerCapabilities(hostConfig *config.HostConfig) ([]string, error) {
+	if hostConfig.Privileged {
+		return capabilites.AllCapabilities(), nil
+	}
+	return capabilites.TweakCapabilities(capabilites.DefaultCapabilities(), hostConfig.CapAdd, hostConfig.CapDrop)
+}
+
+// DefaultEnv 返回容器进程的默认环境变量，容器配置中的同名变量会覆盖它们。
+// HOME 由init进程根据容器用户的passwd记录设置
+func DefaultEnv(cfg *config.Config) []string {
+	env := []string{defaultPathEnv, "HOSTNAME=" + cfg.Hostname}
+	if cfg.Tty {
+		env = append(env, defaultTermEnv)
+	}
+	return env
+}
+
+// workingDir 返回用户命令的工作目录，没有指定时为根目录
+func workingDir(dir string) string {
+	if dir == "" {
+		return "/"
+	}
+	return filepath.Clean(dir)
+}
+
+// defaultMounts 返回每个容器都需要的挂载：/proc、/dev 和 /sys。
+// 特权容器bind mount宿主机的 /dev，并以读写方式挂载 /sys；非特权容器的 /dev 是tmpfs，/sys 只读
+func defaultMounts(privileged bool) []*config.Mount {
+	mounts := []*config.Mount{
+		{
+			Source:      "proc",
+			Destination: "/proc",
+			Device:      "proc",
+			Flags:       unix.MS_NOEXEC | unix.MS_NOSUID | unix.MS_NODEV,
+		},
+	}
+	sysfsFlags := unix.MS_NOEXEC | unix.MS_NOSUID | unix.MS_NODEV
+	if privileged {
+		mounts = append(mounts, &config.Mount{
+			Source:      "/dev",
+			Destination: "/dev",
+			Device:      "bind",
+			Flags:       unix.MS_BIND | unix.MS_REC,
+		})
+	} else {
+		mounts = append(mounts, &config.Mount{
+			Source:      "tmpfs",
+			Destination: "/dev",
+			Device:      "tmpfs",
+			Flags:       unix.MS_NOSUID | unix.MS_STRICTATIME,
+			Data:        "mode=755",
+		})
+		sysfsFlags |= unix.MS_RDONLY
+	}
+	return append(mounts, &config.Mount{
+		Source:      "sysfs",
+		Destination: "/sys",
+		Device:      "sysfs",
+		Flags:       sysfsFlags,
+	})
+}
+
+// Send 将引导消息写入管道并关闭写端，init进程读到EOF后开始解析
+func (b *Bootstrap) Send(w io.WriteCloser) error {
+	err := json.NewEncoder(w).Encode(b)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Errorf("send bootstrap message error %v", err)
+	}
+	return nil
+}
+
+// readBootstrap 读取并校验父进程发送的引导消息
+func readBootstrap(r io.Reader) (*Bootstrap, error) {
+	var b Bootstrap
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, errors.Errorf("decode bootstrap message error %v", err)
+	}
+	if b.Version != BootstrapVersion {
+		return nil, errors.Errorf("unsupported bootstrap message version %d", b.Version)
+	}
+	if len(b.Args) == 0 {
+		return nil, errors.New("bootstrap message has no command")
+	}
+	if b.Capabilities == nil {
+		return nil, errors.New("bootstrap message has no capabilities")
+	}
+	return &b, nil
+}
+
+// containerRlimits 将 --ulimit 转换为init进程设置的资源限制
+func containerRlimits(ulimits []*units.Ulimit) ([]config.Rlimit, error) {
+	var rlimits []config.Rlimit
+	for _, ul := range ulimits {
+		rl, err := ul.GetRlimit()
+		if err != nil {
+			return nil, err
+		}
+		rlimits = append(rlimits, config.Rlimit{Type: rl.Type, Hard: rl.Hard, Soft: rl.Soft})
+	}
+	return rlimits, nil
+}
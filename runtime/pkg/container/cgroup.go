@@ -0,0 +1,40 @@
+package container
+
+import (
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/cgroups"
+	"github.com/DeJeune/sudocker/runtime/pkg/cgroups/fs"
+	"github.com/DeJeune/sudocker/runtime/pkg/cgroups/fs2"
+)
+
+// cgroupParent 是所有容器cgroup的父cgroup
+const cgroupParent = "/sudocker"
+
+// CgroupManager 是容器需要的cgroup操作，cgroup v1 和 v2 分别由 fs.Manager 和 fs2.BaseManager 实现
+type CgroupManager interface {
+	Apply(pid int) error
+	Set(r *config.Resources) error
+	Destroy() error
+	GetAllPids() ([]int, error)
+	Freeze(state config.FreezerState) error
+	GetFreezerState() (config.FreezerState, error)
+	OOMKillCount() (uint64, error)
+}
+
+// NewCgroupManager 返回容器对应的cgroup管理器，cgroup v2 的主机上使用统一层级。cgroup路径只由容器ID决定，
+// 因此之后的命令（如 start、kill、pause）可以重新构造出init进程所在的cgroup
+func NewCgroupManager(containerId string, resources *config.Resources) (CgroupManager, error) {
+	if resources == nil {
+		resources = &config.Resources{}
+	}
+	cg := &config.Cgroup{
+		Name:      containerId,
+		Parent:    cgroupParent,
+		Rootless:  false,
+		Resources: resources,
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		return fs2.NewManager(cg, "")
+	}
+	return fs.NewManager(cg, nil)
+}
@@ -0,0 +1,44 @@
+package container
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// NewConsole 为容器分配一个伪终端，返回master和slave两端。slave作为容器init进程的终端，
+// master由monitor进程持有，用来转发终端的输入输出
+func NewConsole() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, errors.Errorf("open /dev/ptmx error %v", err)
+	}
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		_ = master.Close()
+		return nil, nil, errors.Errorf("unlock pty error %v", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, errors.Errorf("get pty number error %v", err)
+	}
+	slavePath := fmt.Sprintf("/dev/pts/%d", n)
+	slave, err := os.OpenFile(slavePath, os.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, errors.Errorf("open %s error %v", slavePath, err)
+	}
+	return master, slave, nil
+}
+
+// ResizeConsole 修改伪终端的大小
+func ResizeConsole(console *os.File, height, width uint) error {
+	ws := &unix.Winsize{Row: uint16(height), Col: uint16(width)}
+	if err := unix.IoctlSetWinsize(int(console.Fd()), unix.TIOCSWINSZ, ws); err != nil {
+		return errors.Errorf("resize console error %v", err)
+	}
+	return nil
+}
@@ -1,22 +1,103 @@
 package container
 
 import (
+	"os"
+	"syscall"
 	"time"
 
 	"github.com/DeJeune/sudocker/runtime/config"
 )
 
+// SchemaVersion 是当前写入config.json的容器记录的格式版本，记录中增加持久化的字段时需要递增。
+// 没有该字段的旧记录只包含下面的摘要信息，无法还原出完整的创建配置。
+// 版本2增加了退出状态、重启次数、monitor进程、init进程的启动时间以及capabilities
+const SchemaVersion = "2"
+
 type Info struct {
-	Pid         string   `json:"pid"`
-	Id          string   `json:"id"`
-	ImageName   string   `json:"image_name"`
-	Command     string   `json:"command"`
-	Created     string   `json:"created_time"`
-	Name        string   `json:"container_name"`
-	Volumes     []string `json:"volumes"`
-	Status      Status   `json:"status"`
-	PortMapping []string `json:"portmapping"`
-	IP          string   `json:"ip"`
+	SchemaVersion string   `json:"schema_version"`
+	Pid           string   `json:"pid"`
+	Id            string   `json:"id"`
+	ImageName     string   `json:"image_name"`
+	Command       string   `json:"command"`
+	Created       string   `json:"created_time"`
+	Name          string   `json:"container_name"`
+	Volumes       []string `json:"volumes"`
+	Status        Status   `json:"status"`
+	PortMapping   []string `json:"portmapping"`
+	IP            string   `json:"ip"`
+	ExitCode      int      `json:"exit_code"`
+	// ExitSignal 是结束init进程的信号，正常退出时为0
+	ExitSignal int `json:"exit_signal"`
+	// OOMKilled 表示init进程是否因为内存不足被内核杀死
+	OOMKilled bool `json:"oom_killed"`
+	// StartedAt 是容器最近一次启动的时间
+	StartedAt time.Time `json:"started_at"`
+	// FinishedAt 是容器最近一次退出的时间
+	FinishedAt time.Time `json:"finished_at"`
+	// RestartCount 是容器被重启策略自动重启的次数
+	RestartCount int `json:"restart_count"`
+	// ManuallyStopped 表示容器是被 stop 手动停止的，重启策略不会再重启它
+	ManuallyStopped bool `json:"manually_stopped"`
+	// MonitorPid 是看护容器的monitor进程，为0时容器的init进程由前台的 sudocker run 看护
+	MonitorPid int `json:"monitor_pid,omitempty"`
+
+	// State 记录容器的运行时状态以及创建容器时的config.Config
+	State BaseState `json:"state"`
+	// HostConfig 和 NetworkingConfig 是创建容器时的其余配置，start、restart 等命令据此重建容器
+	HostConfig       *config.HostConfig       `json:"host_config,omitempty"`
+	NetworkingConfig *config.NetworkingConfig `json:"networking_config,omitempty"`
+}
+
+// HasFullConfig 判断记录中是否保存了完整的创建配置
+func (info *Info) HasFullConfig() bool {
+	return info.SchemaVersion != "" && info.HostConfig != nil && info.NetworkingConfig != nil
+}
+
+// HasRestartPolicy 判断容器是否设置了重启策略
+func (info *Info) HasRestartPolicy() bool {
+	return info.HostConfig != nil && !info.HostConfig.RestartPolicy.IsNone()
+}
+
+// Monitored 判断容器是否由monitor进程创建并看护，这样的容器的退出状态由monitor记录
+func (info *Info) Monitored() bool {
+	return info.MonitorPid != 0
+}
+
+// MarkStopped 将容器标记为已停止，并清除init进程的信息
+func (info *Info) MarkStopped() {
+	info.Status = Stopped
+	info.Pid = ""
+	info.State.InitProcessPid = 0
+}
+
+// ExitStatus 是容器init进程的退出状态
+type ExitStatus struct {
+	ExitCode  int
+	Signal    syscall.Signal
+	OOMKilled bool
+	ExitedAt  time.Time
+}
+
+// NewExitStatus 根据init进程的ProcessState构造退出状态，被信号杀死时退出码按照惯例记录为 128+信号
+func NewExitStatus(state *os.ProcessState) ExitStatus {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return SignalExitStatus(status.Signal())
+	}
+	return ExitStatus{ExitCode: state.ExitCode(), ExitedAt: time.Now().UTC()}
+}
+
+// SignalExitStatus 构造被信号sig结束的退出状态，用于 stop、kill 这类拿不到真实退出状态的场景
+func SignalExitStatus(sig syscall.Signal) ExitStatus {
+	return ExitStatus{ExitCode: 128 + int(sig), Signal: sig, ExitedAt: time.Now().UTC()}
+}
+
+// SetExited 将容器标记为已停止，并记录init进程的退出状态
+func (info *Info) SetExited(status ExitStatus) {
+	info.MarkStopped()
+	info.ExitCode = status.ExitCode
+	info.ExitSignal = int(status.Signal)
+	info.OOMKilled = status.OOMKilled
+	info.FinishedAt = status.ExitedAt
 }
 
 // Status is the status of a container.
@@ -65,4 +146,7 @@ type BaseState struct {
 
 	// Config is the container's configuration.
 	Config config.Config `json:"config"`
+
+	// Capabilities 是容器进程最近一次启动时的capabilities
+	Capabilities *config.Capabilities `json:"capabilities,omitempty"`
 }
@@ -0,0 +1,63 @@
+package container
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
+)
+
+// ImageConfig 是镜像tar包旁可选的 <image>.json 中记录的运行配置，字段与 docker image inspect 的 Config 一致
+type ImageConfig struct {
+	Env []string `json:"Env,omitempty"`
+}
+
+// LoadImageConfig 读取镜像的运行配置，镜像没有配置文件时返回空的配置
+func LoadImageConfig(imageName string) (*ImageConfig, error) {
+	configPath := utils.GetImageConfig(imageName)
+	content, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ImageConfig{}, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("read image config %s error %v", configPath, err)
+	}
+	var imageConfig ImageConfig
+	if err := json.Unmarshal(content, &imageConfig); err != nil {
+		return nil, errors.Errorf("parse image config %s error %v", configPath, err)
+	}
+	return &imageConfig, nil
+}
+
+// MergeEnv 按顺序合并多组 KEY=VALUE 形式的环境变量，后面的同名变量覆盖前面的，变量保持第一次出现的位置。
+// 没有值的变量（-e VAR 且宿主机上没有VAR）被丢弃
+func MergeEnv(envs ...[]string) []string {
+	var merged []string
+	index := make(map[string]int)
+	for _, env := range envs {
+		for _, kv := range env {
+			key, _, ok := strings.Cut(kv, "=")
+			if !ok || key == "" {
+				continue
+			}
+			if i, exists := index[key]; exists {
+				merged[i] = kv
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
+
+func hasEnv(env []string, key string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return true
+		}
+	}
+	return false
+}
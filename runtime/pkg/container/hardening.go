@@ -0,0 +1,115 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/DeJeune/sudocker/runtime/pkg/devices"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// defaultMaskedPaths 是非特权容器中被屏蔽的路径，文件用 /dev/null 覆盖，目录用只读的tmpfs覆盖
+var defaultMaskedPaths = []string{
+	"/proc/asound",
+	"/proc/acpi",
+	"/proc/interrupts",
+	"/proc/kcore",
+	"/proc/keys",
+	"/proc/latency_stats",
+	"/proc/timer_list",
+	"/proc/timer_stats",
+	"/proc/sched_debug",
+	"/proc/scsi",
+	"/sys/firmware",
+	"/sys/devices/virtual/powercap",
+}
+
+// defaultReadonlyPaths 是非特权容器中重新挂载为只读的路径
+var defaultReadonlyPaths = []string{
+	"/proc/bus",
+	"/proc/fs",
+	"/proc/irq",
+	"/proc/sys",
+	"/proc/sysrq-trigger",
+}
+
+// defaultDevices 是非特权容器的 /dev 中创建的设备文件
+var defaultDevices = []*devices.Device{
+	charDevice("/dev/null", 1, 3),
+	charDevice("/dev/zero", 1, 5),
+	charDevice("/dev/full", 1, 7),
+	charDevice("/dev/random", 1, 8),
+	charDevice("/dev/urandom", 1, 9),
+	charDevice("/dev/tty", 5, 0),
+}
+
+func charDevice(path string, major, minor int64) *devices.Device {
+	return &devices.Device{
+		Rule:     devices.Rule{Type: devices.CharDevice, Major: major, Minor: minor, Permissions: "rwm", Allow: true},
+		Path:     path,
+		FileMode: 0o666,
+	}
+}
+
+// createDevices 在容器的 /dev 中创建设备文件，必须在pivot_root之后调用
+func createDevices(devs []*devices.Device) error {
+	// 不受init进程umask的影响
+	oldMask := unix.Umask(0)
+	defer unix.Umask(oldMask)
+	for _, d := range devs {
+		if !d.Type.CanMknod() {
+			return errors.Errorf("%c is not a valid device type for device %s", d.Type, d.Path)
+		}
+		dev, err := d.Mkdev()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(d.Path), 0o755); err != nil {
+			return err
+		}
+		mode := syscallMode(d.FileMode)
+		switch d.Type {
+		case devices.BlockDevice:
+			mode |= unix.S_IFBLK
+		case devices.CharDevice:
+			mode |= unix.S_IFCHR
+		case devices.FifoDevice:
+			mode |= unix.S_IFIFO
+		}
+		if err := unix.Mknod(d.Path, mode, int(dev)); err != nil && !errors.Is(err, unix.EEXIST) {
+			return &os.PathError{Op: "mknod", Path: d.Path, Err: err}
+		}
+		if err := os.Chown(d.Path, int(d.Uid), int(d.Gid)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maskPath 屏蔽容器内的路径，路径不存在时忽略
+func maskPath(path string) error {
+	if err := mount("/dev/null", path, "", unix.MS_BIND, ""); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, unix.ENOTDIR) {
+			return mount("tmpfs", path, "tmpfs", unix.MS_RDONLY, "")
+		}
+		return err
+	}
+	return nil
+}
+
+// readonlyPath 将容器内的路径bind mount到自身后重新挂载为只读，保留原有的nosuid、nodev、noexec，路径不存在时忽略
+func readonlyPath(path string) error {
+	if err := mount(path, path, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	flags := uintptr(st.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
+	return mount("", path, "", flags|unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY, "")
+}
@@ -0,0 +1,72 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/pkg/errors"
+)
+
+// defaultHosts 是容器 /etc/hosts 中固定的回环和IPv6条目，与 docker 保持一致
+var defaultHosts = []string{
+	"127.0.0.1\tlocalhost",
+	"::1\tlocalhost ip6-localhost ip6-loopback",
+	"fe00::0\tip6-localnet",
+	"ff00::0\tip6-mcastprefix",
+	"ff02::1\tip6-allnodes",
+	"ff02::2\tip6-allrouters",
+}
+
+// WriteHostFiles 在容器的rootfs中写入与主机名一致的 /etc/hostname 和 /etc/hosts，
+// ip为容器的IP地址，为空时 /etc/hosts 中只有固定的条目。容器每次启动都会重新生成
+func WriteHostFiles(containerId string, cfg *config.Config, ip string) error {
+	rootfs := utils.GetMerged(containerId)
+	if err := writeRootfsFile(rootfs, "/etc/hostname", cfg.Hostname+"\n"); err != nil {
+		return err
+	}
+	hosts := append([]string(nil), defaultHosts...)
+	if ip != "" {
+		names := cfg.Hostname
+		if cfg.Domainname != "" {
+			names = fmt.Sprintf("%s.%s %s", cfg.Hostname, cfg.Domainname, cfg.Hostname)
+		}
+		hosts = append(hosts, ip+"\t"+names)
+	}
+	return writeRootfsFile(rootfs, "/etc/hosts", strings.Join(hosts, "\n")+"\n")
+}
+
+// SetupWorkdir 在容器的rootfs中创建工作目录，目录已经存在时不做处理。
+// 路径在rootfs内解析，镜像中指向rootfs之外的符号链接不会在宿主机上创建目录
+func SetupWorkdir(containerId, workdir string) error {
+	if workdir == "" {
+		return nil
+	}
+	path, err := securejoin.SecureJoin(utils.GetMerged(containerId), workdir)
+	if err != nil {
+		return errors.WithMessagef(err, "resolve working directory %s in rootfs", workdir)
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return errors.WithMessagef(err, "create working directory %s", workdir)
+	}
+	return nil
+}
+
+// writeRootfsFile 写入rootfs中的文件。镜像中的文件可能是符号链接，路径在rootfs内解析，不会写到宿主机的其他位置
+func writeRootfsFile(rootfs, name, content string) error {
+	path, err := securejoin.SecureJoin(rootfs, name)
+	if err != nil {
+		return errors.WithMessagef(err, "resolve %s in rootfs", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.WithMessagef(err, "mkdir %s", filepath.Dir(path))
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return errors.WithMessagef(err, "write %s", name)
+	}
+	return nil
+}
@@ -1,20 +1,28 @@
 package container
 
 import (
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 
+	"github.com/DeJeune/sudocker/runtime/pkg/capabilites"
+	"github.com/DeJeune/sudocker/runtime/pkg/seccomp"
+	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 
 	"github.com/sirupsen/logrus"
 )
 
-func setupMount() error {
+// execFifoFd 是父进程通过ExtraFiles传递进来的exec.fifo，见NewParentProcess
+const execFifoFd = 4
+
+// setupMount 将容器的rootfs切换为当前目录，并按顺序完成引导消息中的挂载、设备文件和路径屏蔽
+func setupMount(bootstrap *Bootstrap) error {
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -23,19 +31,50 @@ func setupMount() error {
 	if err = mount("", "/", "", syscall.MS_PRIVATE|syscall.MS_REC, ""); err != nil {
 		return err
 	}
-	if err = pivotRoot(pwd); err != nil {
-		return err
+	// bind mount的源路径在宿主机上，需要在 pivotRoot 之前挂载到rootfs中
+	for _, m := range bootstrap.Mounts {
+		if !m.IsBind() {
+			continue
+		}
+		dest, err := securejoin.SecureJoin(pwd, m.Destination)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dest, 0o755); err != nil {
+			return errors.WithMessagef(err, "mkdir mountpoint %s", m.Destination)
+		}
+		if err := mount(m.Source, dest, m.Device, uintptr(m.Flags), m.Data); err != nil {
+			return err
+		}
 	}
-	// 挂载/proc文件系统
-	defaultMountFlags := syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV
-	if err = mount("proc", "/proc", "proc", uintptr(defaultMountFlags), ""); err != nil {
+	if err = pivotRoot(pwd); err != nil {
 		return err
 	}
-	// 由于前面 pivotRoot 切换了 rootfs，因此这里重新 mount 一下 /dev 目录
-	// tmpfs 是基于 件系 使用 RAM、swap 分区来存储。
+	// 由于前面 pivotRoot 切换了 rootfs，/proc 和 /dev 需要在这里重新挂载。
 	// 不挂载 /dev，会导致容器内部无法访问和使用许多设备，这可能导致系统无法正常工作
-	if err = mount("tmpfs", "/dev", "tmpfs", syscall.MS_NOSUID|syscall.MS_STRICTATIME, "mode=755"); err != nil {
-		return err
+	for _, m := range bootstrap.Mounts {
+		if m.IsBind() {
+			continue
+		}
+		if err := os.MkdirAll(m.Destination, 0o755); err != nil {
+			return errors.WithMessagef(err, "mkdir mountpoint %s", m.Destination)
+		}
+		if err := mount(m.Source, m.Destination, m.Device, uintptr(m.Flags), m.Data); err != nil {
+			return err
+		}
+	}
+	if err := createDevices(bootstrap.Devices); err != nil {
+		return errors.WithMessage(err, "create devices")
+	}
+	for _, path := range bootstrap.MaskedPaths {
+		if err := maskPath(path); err != nil {
+			return errors.WithMessagef(err, "mask path %s", path)
+		}
+	}
+	for _, path := range bootstrap.ReadonlyPaths {
+		if err := readonlyPath(path); err != nil {
+			return errors.WithMessagef(err, "make path %s read-only", path)
+		}
 	}
 	return nil
 }
@@ -75,46 +114,105 @@ func pivotRoot(root string) error {
 }
 
 func RunContainerInitProcess() error {
-	// mount /proc文件系统
-	if err := setupMount(); err != nil {
+	// capabilities 和 bounding set 只对当前线程生效，init进程需要在同一个线程上设置并执行用户命令
+	runtime.LockOSThread()
+	// fd 3 是父进程通过ExtraFiles传递进来的管道的读端，父进程写入引导消息后关闭写端
+	pipe := os.NewFile(uintptr(3), "pipe")
+	bootstrap, err := readBootstrap(pipe)
+	_ = pipe.Close()
+	if err != nil {
+		return err
+	}
+	if err := setupMount(bootstrap); err != nil {
 		return errors.Errorf("mount failed. %v", err)
 	}
-	cmdArray := readUserCommand()
-	if len(cmdArray) == 0 {
-		return errors.New("run container get user command error, cmdArray is nil")
+	if bootstrap.Hostname != "" {
+		if err := unix.Sethostname([]byte(bootstrap.Hostname)); err != nil {
+			return errors.WithMessage(err, "set hostname")
+		}
+	}
+	if bootstrap.Domainname != "" {
+		if err := unix.Setdomainname([]byte(bootstrap.Domainname)); err != nil {
+			return errors.WithMessage(err, "set domainname")
+		}
+	}
+	for _, rlimit := range bootstrap.Rlimits {
+		if err := unix.Setrlimit(rlimit.Type, &unix.Rlimit{Max: rlimit.Hard, Cur: rlimit.Soft}); err != nil {
+			return errors.WithMessagef(err, "set rlimit %d", rlimit.Type)
+		}
+	}
+	execUser, err := ResolveUser("/", bootstrap.User, bootstrap.AdditionalGroups)
+	if err != nil {
+		return err
+	}
+	if !hasEnv(bootstrap.Env, "HOME") {
+		bootstrap.Env = append(bootstrap.Env, "HOME="+execUser.Home)
+	}
+	caps, err := capabilites.New(bootstrap.Capabilities)
+	if err != nil {
+		return errors.WithMessage(err, "load capabilities")
+	}
+	if err := waitForStart(); err != nil {
+		return errors.WithMessage(err, "wait for container start")
+	}
+	// 与 docker 一致，默认不设置no_new_privs，容器内的setuid程序仍然可用，只有 --security-opt no-new-privileges 才设置。
+	// 没有设置no_new_privs时，加载过滤器需要 CAP_SYS_ADMIN，因此在切换用户和收紧capabilities之前加载
+	if !bootstrap.NoNewPrivileges && len(bootstrap.Seccomp) > 0 {
+		if err := seccomp.Load(bootstrap.Seccomp); err != nil {
+			return err
+		}
+	}
+	if err := finalizeProcess(caps, execUser); err != nil {
+		return err
+	}
+	if err := os.Chdir(bootstrap.Cwd); err != nil {
+		return errors.WithMessagef(err, "chdir to %s", bootstrap.Cwd)
+	}
+	// 在容器的环境变量而不是init进程自己的环境变量中查找用户命令
+	os.Clearenv()
+	for _, kv := range bootstrap.Env {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			_ = os.Setenv(key, value)
+		}
 	}
-	path, err := exec.LookPath(cmdArray[0])
+	path, err := exec.LookPath(bootstrap.Args[0])
 	if err != nil {
 		logrus.Errorf("Exec loop path error %v", err)
 		return err
 	}
 	logrus.Infof("Find path %s", path)
-	if err := syscall.Exec(path, cmdArray[0:], os.Environ()); err != nil {
-		logrus.Errorf("RunContainerInitProcess exec :" + err.Error())
+	// 设置了no_new_privs时过滤器在最后加载，之前的步骤不受容器的seccomp配置限制
+	if bootstrap.NoNewPrivileges {
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return errors.Errorf("set no_new_privs error %v", err)
+		}
+		if len(bootstrap.Seccomp) > 0 {
+			if err := seccomp.Load(bootstrap.Seccomp); err != nil {
+				return err
+			}
+		}
+	}
+	// 执行成功时不会返回。失败时返回错误，init进程以非0的退出码退出，monitor据此记录失败并按照重启策略处理
+	if err := syscall.Exec(path, bootstrap.Args, bootstrap.Env); err != nil {
+		return &os.PathError{Op: "exec", Path: path, Err: err}
 	}
-
 	return nil
 }
 
-func readUserCommand() []string {
-	// uintptr(3 ）就是指 index 为3的文件描述符，也就是传递进来的管道的另一端，至于为什么是3，具体解释如下：
-	/*	因为每个进程默认都会有3个文件描述符，分别是标准输入、标准输出、标准错误。这3个是子进程一创建的时候就会默认带着的，
-		前面通过ExtraFiles方式带过来的 readPipe 理所当然地就成为了第4个。
-		在进程中可以通过index方式读取对应的文件，比如
-		index0：标准输入
-		index1：标准输出
-		index2：标准错误
-		index3：带过来的第一个FD，也就是readPipe
-		由于可以带多个FD过来，所以这里的3就不是固定的了。
-		比如像这样：cmd.ExtraFiles = []*os.File{a,b,c,readPipe} 这里带了4个文件过来，分别的index就是3,4,5,6
-		那么我们的 readPipe 就是 index6,读取时就要像这样：pipe := os.NewFile(uintptr(6), "pipe")
-	*/
-	pipe := os.NewFile(uintptr(3), "pipe")
-	msg, err := io.ReadAll(pipe)
+// waitForStart 以写方式打开exec.fifo，在 sudocker start 打开读端之前会一直阻塞，
+// 从而让容器停留在Created状态。写入一个字节后返回，随后init进程执行用户命令
+func waitForStart() error {
+	// 此时已经挂载了容器自己的/proc，/proc/self/fd/4 指向父进程传递进来的fifo
+	fifoPath := "/proc/self/fd/" + strconv.Itoa(execFifoFd)
+	fd, err := unix.Open(fifoPath, unix.O_WRONLY|unix.O_CLOEXEC, 0)
 	if err != nil {
-		logrus.Errorf("init read pipe error %v", err)
-		return nil
+		return &os.PathError{Op: "open exec fifo", Path: fifoPath, Err: err}
 	}
-	msgStr := string(msg)
-	return strings.Split(msgStr, " ")
+	defer unix.Close(fd)
+	// O_PATH 的fd不需要带入用户进程
+	unix.CloseOnExec(execFifoFd)
+	if _, err := unix.Write(fd, []byte("0")); err != nil {
+		return &os.PathError{Op: "write exec fifo", Path: fifoPath, Err: err}
+	}
+	return nil
 }
@@ -0,0 +1,99 @@
+package container
+
+import (
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// KillContainer 向容器的init进程发送信号，all为true时向容器cgroup中的所有进程发送信号。
+// 如果进程随后退出，则将容器状态修改为Stopped；信号没有让进程退出时（如SIGHUP）容器状态保持不变。
+// 暂停中的容器只有SIGKILL会立即生效。init进程已经不存在时同时返回修正之前的容器信息，调用者据此释放容器的网络资源
+func KillContainer(ref string, sig syscall.Signal, all bool) (*Info, error) {
+	containerInfo, err := GetInfoByContainerId(ref)
+	if err != nil {
+		return nil, errors.Errorf("Get container %s info error %v", ref, err)
+	}
+	containerId := containerInfo.Id
+	if containerInfo.Status != Running && containerInfo.Status != Paused {
+		return nil, errors.Errorf("Container %s is not running", containerId)
+	}
+	// init进程已经退出，或者pid已经被其他进程复用，不能再向它发送信号
+	if !InitProcessAlive(containerInfo) {
+		stale, err := ReconcileContainer(containerId)
+		if err != nil {
+			return stale, err
+		}
+		return stale, errors.Errorf("Container %s is not running", containerId)
+	}
+	pid, err := strconv.Atoi(containerInfo.Pid)
+	if err != nil {
+		return nil, errors.Errorf("Conver pid from string to int error %v", err)
+	}
+	if all {
+		if err := signalAllProcesses(containerId, sig); err != nil {
+			return nil, errors.Errorf("Kill container %s error %v", containerId, err)
+		}
+	} else if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+		return nil, errors.Errorf("Kill container %s error %v", containerId, err)
+	}
+
+	timeout := time.Second
+	if sig == syscall.SIGKILL {
+		timeout = 10 * time.Second
+		// 被冻结的进程无法处理SIGKILL，解冻后才会退出；其他信号在 unpause 之后才会被处理
+		if err := thawPaused(containerInfo); err != nil {
+			return nil, err
+		}
+	}
+	if !waitForExit(pid, timeout) {
+		return nil, nil
+	}
+	return nil, recordSignalExit(containerId, containerInfo.Pid, sig)
+}
+
+// recordSignalExit 在容器的init进程被sig结束后记录退出状态。init进程的父进程已经记录了真实的退出状态，
+// 或者容器已经被重新启动时不修改记录
+func recordSignalExit(containerId, pid string, sig syscall.Signal) error {
+	_, err := UpdateContainerInfo(containerId, func(info *Info) error {
+		if info.Pid != pid {
+			return ErrSkipUpdate
+		}
+		info.SetExited(SignalExitStatus(sig))
+		return nil
+	})
+	return err
+}
+
+// signalAllProcesses 向容器cgroup中的所有进程发送信号
+func signalAllProcesses(containerId string, sig syscall.Signal) error {
+	manager, err := NewCgroupManager(containerId, nil)
+	if err != nil {
+		return err
+	}
+	pids, err := manager.GetAllPids()
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+			logrus.Warnf("kill process %d in container %s: %v", pid, containerId, err)
+		}
+	}
+	return nil
+}
+
+// waitForExit 等待pid对应的进程退出，超时返回false。timeout为负数时一直等待
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for IsProcessAlive(pid) {
+		if timeout >= 0 && time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
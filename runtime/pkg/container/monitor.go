@@ -0,0 +1,120 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
+)
+
+// monitor进程控制socket支持的命令
+const (
+	// MonitorCommandState 查询monitor进程以及容器init进程的pid
+	MonitorCommandState = "state"
+	// MonitorCommandSignal 通过monitor进程向容器的init进程发送信号
+	MonitorCommandSignal = "signal"
+	// MonitorCommandWait 阻塞直到容器当前的init进程退出，返回退出码
+	MonitorCommandWait = "wait"
+	// MonitorCommandAttach 响应之后连接成为容器标准输入输出的数据流
+	MonitorCommandAttach = "attach"
+	// MonitorCommandResize 修改容器终端的大小
+	MonitorCommandResize = "resize"
+)
+
+// MonitorRequest 是发送给monitor进程控制socket的请求，每个连接一个请求
+type MonitorRequest struct {
+	Command string `json:"command"`
+	Signal  int    `json:"signal,omitempty"`
+	// Stdin 表示attach时是否将客户端的输入写入容器的标准输入
+	Stdin  bool `json:"stdin,omitempty"`
+	Height uint `json:"height,omitempty"`
+	Width  uint `json:"width,omitempty"`
+}
+
+// MonitorResponse 是monitor进程对请求的响应，Error不为空时表示请求失败
+type MonitorResponse struct {
+	MonitorPid int    `json:"monitor_pid"`
+	Pid        int    `json:"pid"`
+	ExitCode   int    `json:"exit_code"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MonitorSocketPath 返回容器monitor进程控制socket的路径
+func MonitorSocketPath(containerId string) string {
+	return path.Join(fmt.Sprintf(utils.InfoLocFormat, containerId), utils.MonitorSock)
+}
+
+// CallMonitor 通过控制socket向容器的monitor进程发送请求并等待响应
+func CallMonitor(ctx context.Context, containerId string, req MonitorRequest) (*MonitorResponse, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", MonitorSocketPath(containerId))
+	if err != nil {
+		return nil, errors.Errorf("connect to monitor of container %s error %v", containerId, err)
+	}
+	defer conn.Close()
+	// ctx取消时关闭连接，使阻塞的读取返回
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, errors.Errorf("send request to monitor of container %s error %v", containerId, err)
+	}
+	var resp MonitorResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, errors.Errorf("read response from monitor of container %s error %v", containerId, err)
+	}
+	if resp.Error != "" {
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// MonitorStream 是attach到容器后与monitor进程之间的双向数据流。没有分配终端的容器，
+// 输出按照 stdcopy 的格式区分stdout和stderr
+type MonitorStream struct {
+	*net.UnixConn
+	reader *bufio.Reader
+}
+
+func (s *MonitorStream) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+// AttachMonitor 请求monitor进程attach到容器的标准输入输出，返回之后的数据流。
+// stdin为true时写入数据流的内容会被转发到容器的标准输入
+func AttachMonitor(ctx context.Context, containerId string, stdin bool) (*MonitorStream, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", MonitorSocketPath(containerId))
+	if err != nil {
+		return nil, errors.Errorf("connect to monitor of container %s error %v", containerId, err)
+	}
+	if err := json.NewEncoder(conn).Encode(MonitorRequest{Command: MonitorCommandAttach, Stdin: stdin}); err != nil {
+		_ = conn.Close()
+		return nil, errors.Errorf("send request to monitor of container %s error %v", containerId, err)
+	}
+	// 响应之后紧跟着容器的输出，逐行读取响应，避免解码时把输出读进缓冲区
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Errorf("read response from monitor of container %s error %v", containerId, err)
+	}
+	var resp MonitorResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		_ = conn.Close()
+		return nil, errors.Errorf("decode response from monitor of container %s error %v", containerId, err)
+	}
+	if resp.Error != "" {
+		_ = conn.Close()
+		return nil, errors.New(resp.Error)
+	}
+	return &MonitorStream{UnixConn: conn.(*net.UnixConn), reader: reader}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/DeJeune/sudocker/runtime/pkg/mountinfo"
 	"github.com/DeJeune/sudocker/runtime/pkg/userns"
 	"github.com/DeJeune/sudocker/runtime/utils"
 	"github.com/pkg/errors"
@@ -162,6 +163,15 @@ func unmount(target string, flags int) error {
 	return nil
 }
 
+// mounted 判断target是否是一个挂载点
+func mounted(target string) (bool, error) {
+	mounts, err := mountinfo.GetMounts(mountinfo.SingleEntryFilter(target))
+	if err != nil {
+		return false, err
+	}
+	return len(mounts) > 0, nil
+}
+
 // syscallMode returns the syscall-specific mode bits from Go's portable mode bits.
 // Copy from https://cs.opensource.google/go/go/+/refs/tags/go1.20.7:src/os/file_posix.go;l=61-75
 func syscallMode(i fs.FileMode) (o uint32) {
@@ -5,15 +5,18 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"syscall"
 
-	"github.com/DeJeune/sudocker/cmd"
 	"github.com/DeJeune/sudocker/runtime/config"
 	"github.com/DeJeune/sudocker/runtime/utils"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 )
 
-func NewParentProcess(ctx context.Context, cli cmd.Cli, config *config.Config, hostConfig *config.HostConfig, containerId string) (*exec.Cmd, *os.File) {
+// NewParentProcess 构造容器的init进程，init进程的标准输入输出由monitor进程设置。
+// init进程启动后会阻塞在exec.fifo上，直到 sudocker start 将其解除
+func NewParentProcess(ctx context.Context, config *config.Config, hostConfig *config.HostConfig, containerId string) (*exec.Cmd, *os.File) {
 	readPipe, writePipe, err := os.Pipe()
 	if err != nil {
 		logrus.Errorf("New pipe error %v", err)
@@ -24,32 +27,41 @@ func NewParentProcess(ctx context.Context, cli cmd.Cli, config *config.Config, h
 		Cloneflags: syscall.CLONE_NEWUTS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNS |
 			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC,
 	}
-	if config.Tty {
-		cmd.Stdin = cli.In()
-		cmd.Stdout = cli.Out()
-		cmd.Stderr = cli.Err()
-	} else {
-		// 对于后台运行容器，将 stdout、stderr 重定向到日志文件中，便于后续查看
-		dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
-		if err := os.MkdirAll(dirPath, 0o622); err != nil {
-			logrus.Errorf("NewParentProcess mkdir %s error %v", dirPath, err)
-			return nil, nil
-		}
-		stdLogFilePath := dirPath + utils.GetLogfile(containerId)
-		stdLogFile, err := os.Create(stdLogFilePath)
-		if err != nil {
-			logrus.Errorf("NewParentProcess create file %s error %v", stdLogFilePath, err)
-			return nil, nil
-		}
-		cmd.Stdout = stdLogFile
-		cmd.Stderr = stdLogFile
-	}
-	cmd.ExtraFiles = []*os.File{readPipe}
+	dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
+	if err := os.MkdirAll(dirPath, 0o622); err != nil {
+		logrus.Errorf("NewParentProcess mkdir %s error %v", dirPath, err)
+		return nil, nil
+	}
+	execFifo, err := createExecFifo(dirPath)
+	if err != nil {
+		logrus.Errorf("NewParentProcess create exec fifo error %v", err)
+		return nil, nil
+	}
+	// readPipe 为子进程的fd 3，execFifo 为fd 4
+	cmd.ExtraFiles = []*os.File{readPipe, execFifo}
 	if err := NewStorageDriver(containerId, config.Image, hostConfig.Binds); err != nil {
 		logrus.Errorf("mount storage driver failed: %v", err)
 		return nil, nil
 	}
 	cmd.Dir = utils.GetMerged(containerId)
-	cmd.Env = append(os.Environ(), config.Env...)
+	// init进程不继承宿主机的环境变量，以免泄露给容器；容器的环境变量通过引导消息传递
+	cmd.Env = []string{}
 	return cmd, writePipe
 }
+
+// createExecFifo 在容器目录下创建exec.fifo，并以O_PATH方式打开传递给init进程。
+// init进程在pivot_root之后通过/proc/self/fd重新以写方式打开它，从而阻塞到有读者出现为止
+func createExecFifo(dirPath string) (*os.File, error) {
+	fifoName := path.Join(dirPath, utils.ExecFifoName)
+	if err := os.Remove(fifoName); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := unix.Mkfifo(fifoName, 0o622); err != nil {
+		return nil, &os.PathError{Op: "mkfifo", Path: fifoName, Err: err}
+	}
+	fd, err := unix.Open(fifoName, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: fifoName, Err: err}
+	}
+	return os.NewFile(uintptr(fd), fifoName), nil
+}
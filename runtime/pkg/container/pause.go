@@ -0,0 +1,73 @@
+package container
+
+import (
+	"github.com/DeJeune/sudocker/runtime/config"
+	"github.com/pkg/errors"
+)
+
+// PauseContainer 通过cgroup freezer冻结容器中的所有进程，并将容器状态修改为Paused
+func PauseContainer(ref string) error {
+	containerInfo, err := GetInfoByContainerId(ref)
+	if err != nil {
+		return errors.Errorf("Get container %s info error %v", ref, err)
+	}
+	containerId := containerInfo.Id
+	switch containerInfo.Status {
+	case Running:
+	case Paused:
+		return errors.Errorf("Container %s is already paused", containerId)
+	default:
+		return errors.Errorf("Container %s is not running", containerId)
+	}
+	if err := freezeContainer(containerId, config.Frozen); err != nil {
+		return err
+	}
+	_, err = UpdateContainerInfo(containerId, func(info *Info) error {
+		info.Status = Paused
+		return nil
+	})
+	return err
+}
+
+// UnpauseContainer 解冻容器中的所有进程，并将容器状态修改为Running
+func UnpauseContainer(ref string) error {
+	containerInfo, err := GetInfoByContainerId(ref)
+	if err != nil {
+		return errors.Errorf("Get container %s info error %v", ref, err)
+	}
+	containerId := containerInfo.Id
+	if containerInfo.Status != Paused {
+		return errors.Errorf("Container %s is not paused", containerId)
+	}
+	if err := freezeContainer(containerId, config.Thawed); err != nil {
+		return err
+	}
+	_, err = UpdateContainerInfo(containerId, func(info *Info) error {
+		// 解冻期间容器可能已经退出并被记录
+		if info.Status != Paused {
+			return ErrSkipUpdate
+		}
+		info.Status = Running
+		return nil
+	})
+	return err
+}
+
+func freezeContainer(containerId string, state config.FreezerState) error {
+	f, err := NewCgroupManager(containerId, nil)
+	if err != nil {
+		return errors.WithMessagef(err, "get cgroup of container %s", containerId)
+	}
+	if err := f.Freeze(state); err != nil {
+		return errors.WithMessagef(err, "set freezer state of container %s to %s", containerId, state)
+	}
+	return nil
+}
+
+// thawPaused 解冻暂停中的容器，使发送给它的信号能够被处理。只在容器即将退出时使用，不修改容器状态
+func thawPaused(containerInfo *Info) error {
+	if containerInfo.Status != Paused {
+		return nil
+	}
+	return freezeContainer(containerInfo.Id, config.Thawed)
+}
@@ -1,50 +1,113 @@
 package container
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path"
+	"strconv"
 
+	"github.com/DeJeune/sudocker/runtime/pkg/store"
 	"github.com/DeJeune/sudocker/runtime/utils"
 	"github.com/pkg/errors"
 )
 
+// infoStore 保存所有容器的config.json，容器信息的读写都经过它
+var infoStore = store.New(utils.InfoLoc, utils.ConfigName)
+
+// ErrSkipUpdate 由 UpdateContainerInfo 的回调返回，表示容器信息不需要修改
+var ErrSkipUpdate = store.ErrSkip
+
+// RecordContainerInfo 加锁后原子地写入完整的容器信息，用于创建容器等整体覆盖记录的场景。
+// 修改已有容器的部分字段时应使用 UpdateContainerInfo，避免覆盖其他进程并发写入的修改
 func RecordContainerInfo(containerInfo *Info) error {
-	jsonBytes, err := json.Marshal(containerInfo)
+	if err := infoStore.Write(containerInfo.Id, containerInfo); err != nil {
+		return errors.WithMessagef(err, "write container %s info failed", containerInfo.Id)
+	}
+	return nil
+}
+
+// UpdateContainerInfo 持有容器的锁读取最新的容器信息，调用fn修改后原子地写回，返回修改后的容器信息。
+// fn返回 ErrSkipUpdate 时不写回
+func UpdateContainerInfo(containerId string, fn func(info *Info) error) (*Info, error) {
+	info := new(Info)
+	err := infoStore.Update(containerId, info, func() error {
+		if err := checkSchemaVersion(info); err != nil {
+			return err
+		}
+		migrateInfo(info)
+		return fn(info)
+	})
 	if err != nil {
-		return errors.WithMessage(err, "container info marshal failed")
+		return nil, err
 	}
-	jsonStr := string(jsonBytes)
-	// 拼接出存储容器信息文件的路径，如果目录不存在则级联创建
-	dirPath := fmt.Sprintf(utils.InfoLocFormat, containerInfo.Id)
-	if err := os.MkdirAll(dirPath, 0o777); err != nil {
-		return errors.WithMessagef(err, "mkdir %s failed", dirPath)
+	return info, nil
+}
+
+// GetInfoByContainerId 根据完整ID、名称或唯一的ID前缀读取容器信息
+func GetInfoByContainerId(ref string) (*Info, error) {
+	containerId, err := ResolveContainerID(ref)
+	if err != nil {
+		return nil, err
 	}
-	// 将容器信息写入文件
-	fileName := path.Join(dirPath, utils.ConfigName)
-	file, err := os.Create(fileName)
+	return readContainerInfo(containerId)
+}
+
+// ListContainerInfos 读取所有容器的信息，无法读取的记录会被跳过并通过onError报告
+func ListContainerInfos(onError func(containerId string, err error)) ([]*Info, error) {
+	ids, err := infoStore.List()
 	if err != nil {
-		return errors.Errorf("create file failed: %v", err)
+		return nil, err
 	}
-	defer file.Close()
-	// if err != nil {
-	// 	return errors.WithMessagef(err, "create file %s failed", fileName)
-	// }
-	if _, err = file.WriteString(jsonStr); err != nil {
-		return errors.WithMessagef(err, "write container info to  file %s failed", fileName)
+	infos := make([]*Info, 0, len(ids))
+	for _, id := range ids {
+		info, err := readContainerInfo(id)
+		if err != nil {
+			if onError != nil {
+				onError(id, err)
+			}
+			continue
+		}
+		infos = append(infos, info)
 	}
-	return nil
+	return infos, nil
 }
 
-func GenerateContainerID() string {
-	return utils.RandStringBytes(utils.IDLength)
+// readContainerInfo 读取完整ID对应的容器信息
+func readContainerInfo(containerId string) (*Info, error) {
+	var containerInfo Info
+	if err := infoStore.Read(containerId, &containerInfo); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(&containerInfo); err != nil {
+		return nil, err
+	}
+	migrateInfo(&containerInfo)
+	return &containerInfo, nil
 }
 
-func DeleteContainerInfo(containerID string) error {
-	dirPath := fmt.Sprintf(utils.InfoLocFormat, containerID)
-	if err := os.RemoveAll(dirPath); err != nil {
-		return errors.Errorf("Remove dir %s error: %v", dirPath, err)
+// checkSchemaVersion 拒绝由更新版本的 sudocker 写入的记录，避免按照旧的格式修改后丢失字段
+func checkSchemaVersion(info *Info) error {
+	if info.SchemaVersion == "" {
+		return nil
+	}
+	version, err := strconv.Atoi(info.SchemaVersion)
+	if err != nil {
+		return errors.Errorf("container %s has invalid schema version %q", info.Id, info.SchemaVersion)
+	}
+	current, _ := strconv.Atoi(SchemaVersion)
+	if version > current {
+		return errors.Errorf("container %s was written with schema version %d, newer than supported version %d",
+			info.Id, version, current)
 	}
 	return nil
 }
+
+// migrateInfo 将旧版本的记录升级到当前的格式，修改后写回时保存为新的版本。
+// 版本1的记录缺少的字段取零值，与没有这些字段时的行为一致：没有monitor进程、不校验init进程的启动时间
+func migrateInfo(info *Info) {
+	if info.SchemaVersion == "" {
+		return
+	}
+	info.SchemaVersion = SchemaVersion
+}
+
+func DeleteContainerInfo(containerID string) error {
+	return infoStore.Delete(containerID)
+}
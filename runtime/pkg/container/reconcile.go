@@ -0,0 +1,115 @@
+package container
+
+import (
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/DeJeune/sudocker/runtime/pkg/system"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// staleExitCode 是init进程在没有sudocker进程看护时消失的容器记录的退出码，拿不到真实的退出状态
+const staleExitCode = 255
+
+// InitProcessStartTime 返回pid对应进程的启动时间，用于之后判断pid是否被其他进程复用
+func InitProcessStartTime(pid int) (uint64, error) {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return stat.StartTime, nil
+}
+
+// InitProcessAlive 判断容器记录中的init进程是否仍然存在，pid已经被其他进程复用时同样认为已经退出。
+// 僵尸进程的父进程还在，稍后会由父进程记录退出状态，因此认为仍然存在
+func InitProcessAlive(info *Info) bool {
+	pid, err := strconv.Atoi(info.Pid)
+	if err != nil {
+		return false
+	}
+	stat, err := system.Stat(pid)
+	if err != nil || stat.State == system.Dead {
+		return false
+	}
+	return info.State.InitProcessStartTime == 0 || stat.StartTime == info.State.InitProcessStartTime
+}
+
+// isStale 判断容器记录是否已经过期：记录中仍有init进程，但进程已经不存在
+func isStale(info *Info) bool {
+	switch info.Status {
+	case Created, Running, Paused:
+		return !InitProcessAlive(info)
+	}
+	return false
+}
+
+// ReconcileContainer 在容器的init进程已经退出或pid已经被复用时，将容器标记为已停止，并卸载容器的文件系统。
+// 返回修正之前的容器信息，调用者据此释放容器的网络资源；容器记录没有过期时返回nil
+func ReconcileContainer(containerId string) (*Info, error) {
+	var stale *Info
+	_, err := UpdateContainerInfo(containerId, func(info *Info) error {
+		if !isStale(info) {
+			return ErrSkipUpdate
+		}
+		before := *info
+		stale = &before
+		info.SetExited(ExitStatus{ExitCode: staleExitCode, ExitedAt: time.Now().UTC()})
+		info.IP = ""
+		return nil
+	})
+	if err != nil || stale == nil {
+		return nil, err
+	}
+	logrus.Infof("container %s init process %s is gone, marked as stopped", containerId, stale.Pid)
+	if err := releaseMounts(stale); err != nil {
+		logrus.Warnf("release mounts of container %s: %v", containerId, err)
+	}
+	return stale, nil
+}
+
+// ReconcileContainers 修正所有过期的容器记录，返回修正之前的容器信息
+func ReconcileContainers() ([]*Info, error) {
+	ids, err := infoStore.List()
+	if err != nil {
+		return nil, err
+	}
+	var reconciled []*Info
+	var errs []error
+	for _, id := range ids {
+		stale, err := ReconcileContainer(id)
+		if err != nil {
+			errs = append(errs, errors.WithMessagef(err, "reconcile container %s", id))
+			continue
+		}
+		if stale != nil {
+			reconciled = append(reconciled, stale)
+		}
+	}
+	if len(errs) > 0 {
+		return reconciled, errs[0]
+	}
+	return reconciled, nil
+}
+
+// releaseMounts 卸载容器残留的volume和overlay挂载，保留overlay的目录以便容器重新启动
+func releaseMounts(info *Info) error {
+	mntPath := utils.GetMerged(info.Id)
+	for _, volume := range info.Volumes {
+		_, containerPath, err := volumeExtract(volume)
+		if err != nil {
+			continue
+		}
+		if ok, _ := mounted(path.Join(mntPath, containerPath)); ok {
+			if err := umountVolume(mntPath, containerPath); err != nil {
+				return err
+			}
+		}
+	}
+	if ok, _ := mounted(mntPath); ok {
+		return unmount(mntPath, 0)
+	}
+	return nil
+}
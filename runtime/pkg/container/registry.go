@@ -0,0 +1,202 @@
+package container
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// validContainerName 是容器名称允许的格式，与 docker 保持一致
+var validContainerName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// nameReservationGrace 是登记名称之后写入容器记录的期限。创建中的容器先登记名称再写入记录，
+// 没有记录的登记只有超过这个期限才被认为已经过期
+const nameReservationGrace = time.Minute
+
+// noSuchContainerError 表示根据名称或ID找不到容器，可以通过 errors.Is(err, os.ErrNotExist) 判断
+type noSuchContainerError struct {
+	ref string
+}
+
+func (e noSuchContainerError) Error() string {
+	return fmt.Sprintf("No such container: %s", e.ref)
+}
+
+func (e noSuchContainerError) Is(target error) bool {
+	return target == os.ErrNotExist
+}
+
+// GenerateContainerID 使用加密随机数生成64位十六进制的容器ID，
+// 短ID全部由数字组成时重新生成，避免与其他数字参数混淆
+func GenerateContainerID() string {
+	b := make([]byte, utils.IDLength/2)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err) // This shouldn't happen
+		}
+		id := hex.EncodeToString(b)
+		if _, err := strconv.ParseUint(TruncateID(id), 10, 64); err == nil {
+			continue
+		}
+		return id
+	}
+}
+
+// TruncateID 返回容器ID的12位短格式
+func TruncateID(id string) string {
+	if len(id) > utils.ShortIDLength {
+		return id[:utils.ShortIDLength]
+	}
+	return id
+}
+
+// ResolveContainerID 将完整ID、容器名称或唯一的ID前缀解析为容器的完整ID，
+// 依次按照完整ID、名称、ID前缀匹配，前缀对应多个容器时返回错误
+func ResolveContainerID(ref string) (string, error) {
+	ref = strings.TrimPrefix(ref, "/")
+	if ref == "" {
+		return "", errors.New("Container name cannot be empty")
+	}
+	if containerExists(ref) {
+		return ref, nil
+	}
+	if id, err := os.Readlink(path.Join(utils.NamesLoc, ref)); err == nil && containerExists(id) {
+		return id, nil
+	}
+
+	ids, err := infoStore.List()
+	if err != nil {
+		return "", err
+	}
+	// 旧版本创建的容器没有在名称目录中登记，通过容器记录中的名称查找
+	if id := findContainerByName(ids, ref); id != "" {
+		return id, nil
+	}
+	var matches []string
+	for _, id := range ids {
+		if strings.HasPrefix(id, ref) {
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", noSuchContainerError{ref: ref}
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.Errorf("multiple IDs found with provided prefix: %s", ref)
+	}
+}
+
+// ReserveName 为容器登记名称，名称已经被其他存在的容器使用时返回错误。
+// 登记过程持有名称目录的文件锁，并发创建同名容器时只有一个能够成功
+func ReserveName(name, containerId string) error {
+	if !validContainerName.MatchString(name) {
+		return errors.Errorf("Invalid container name (%s), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", name)
+	}
+	if err := os.MkdirAll(utils.NamesLoc, 0o700); err != nil {
+		return errors.WithMessagef(err, "mkdir %s failed", utils.NamesLoc)
+	}
+	unlock, err := lockNames()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	link := path.Join(utils.NamesLoc, name)
+	owner, err := os.Readlink(link)
+	switch {
+	case err == nil && owner == containerId:
+		return nil
+	case err == nil && (containerExists(owner) || reservedRecently(link)):
+		return nameConflictError(name, owner)
+	case err == nil:
+		// 上一个使用该名称的容器没有创建成功或者已经不存在，清理过期的登记
+		if err := os.Remove(link); err != nil {
+			return errors.WithMessagef(err, "remove stale name %s", name)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return errors.WithMessagef(err, "read name %s", name)
+	}
+
+	ids, err := infoStore.List()
+	if err != nil {
+		return err
+	}
+	if owner := findContainerByName(ids, name); owner != "" && owner != containerId {
+		return nameConflictError(name, owner)
+	}
+	if err := os.Symlink(containerId, link); err != nil {
+		return errors.WithMessagef(err, "reserve name %s", name)
+	}
+	return nil
+}
+
+// ReleaseName 删除容器登记的名称，名称已经属于其他容器时不做处理
+func ReleaseName(name, containerId string) error {
+	link := path.Join(utils.NamesLoc, name)
+	owner, err := os.Readlink(link)
+	if err != nil || owner != containerId {
+		return nil
+	}
+	if err := os.Remove(link); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithMessagef(err, "release name %s", name)
+	}
+	return nil
+}
+
+// reservedRecently 判断名称是否在 nameReservationGrace 之内登记，此时容器可能还在创建中
+func reservedRecently(link string) bool {
+	fi, err := os.Lstat(link)
+	return err == nil && time.Since(fi.ModTime()) < nameReservationGrace
+}
+
+func nameConflictError(name, owner string) error {
+	return errors.Errorf("Conflict. The container name %q is already in use by container %q. "+
+		"You have to remove (or rename) that container to be able to reuse that name.", name, owner)
+}
+
+// lockNames 对名称目录加排他锁，返回解锁函数
+func lockNames() (func(), error) {
+	dir, err := os.Open(utils.NamesLoc)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "open %s", utils.NamesLoc)
+	}
+	if err := unix.Flock(int(dir.Fd()), unix.LOCK_EX); err != nil {
+		_ = dir.Close()
+		return nil, errors.WithMessagef(err, "lock %s", utils.NamesLoc)
+	}
+	return func() {
+		_ = unix.Flock(int(dir.Fd()), unix.LOCK_UN)
+		_ = dir.Close()
+	}, nil
+}
+
+// containerExists 判断id对应的容器记录是否存在
+func containerExists(id string) bool {
+	return infoStore.Exists(id)
+}
+
+// findContainerByName 在容器记录中查找名称为name的容器，返回其ID
+func findContainerByName(ids []string, name string) string {
+	for _, id := range ids {
+		info, err := readContainerInfo(id)
+		if err != nil {
+			continue
+		}
+		if info.Name == name {
+			return info.Id
+		}
+	}
+	return ""
+}
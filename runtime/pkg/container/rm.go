@@ -1,7 +1,9 @@
 package container
 
 import (
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -13,38 +15,53 @@ type RemoveOptions struct {
 	Force         bool
 }
 
-func RmContainer(containerId string, opts RemoveOptions) error {
+func RmContainer(ref string, opts RemoveOptions) error {
 	force := opts.Force
-	containerId = strings.Trim(containerId, "/")
-	if containerId == "" {
+	ref = strings.Trim(ref, "/")
+	if ref == "" {
 		return errors.New("Container name cannot be empty")
 	}
 
-	containerInfo, err := GetInfoByContainerId(containerId)
+	containerInfo, err := GetInfoByContainerId(ref)
 	if err != nil {
-		return errors.Errorf("Get container %s info error %v", containerId, err)
+		return errors.Errorf("Get container %s info error %v", ref, err)
 	}
+	containerId := containerInfo.Id
 
 	switch containerInfo.Status {
+	case Created: // CREATED 状态容器的init进程阻塞在exec.fifo上，直接杀死后按STOP状态删除
+		if pid, err := strconv.Atoi(containerInfo.Pid); err == nil {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+		fallthrough
 	case Stopped: // STOP 状态容器直接删除即可
 		// 先删除配置目录，再删除rootfs 目录
 		if err = DeleteContainerInfo(containerId); err != nil {
 			logrus.Errorf("Remove container [%s]'s config failed, detail: %v", containerId, err)
 		}
 		DeleteStorageDriver(containerId, containerInfo.Volumes)
+		if err = ReleaseName(containerInfo.Name, containerId); err != nil {
+			logrus.Errorf("Release container [%s]'s name failed, detail: %v", containerId, err)
+		}
+		if cgroupManager, err := NewCgroupManager(containerId, nil); err == nil {
+			if err := cgroupManager.Destroy(); err != nil {
+				logrus.Errorf("Destroy container [%s]'s cgroup failed, detail: %v", containerId, err)
+			}
+		}
 		// if containerInfo.NetworkName != "" { // 清理网络资源
 		// 	if err = network.Disconnect(containerInfo.NetworkName, containerInfo); err != nil {
 		// 		log.Errorf("Remove container [%s]'s config failed, detail: %v", containerId, err)
 		// 		return
 		// 	}
 		// }
-	case Running: // RUNNING 状态容器如果指定了 force 则先 stop 然后再删除
+	case Running, Paused: // RUNNING、PAUSED 状态容器如果指定了 force 则先 stop 然后再删除
 		if !force {
 			return errors.Errorf("Couldn't remove running container [%s], Stop the container before attempting removal or"+
 				" force remove", containerId)
 		}
 		logrus.Infof("force delete running container [%s]", containerId)
-		if err := StopContainer(containerId); err != nil {
+		// 调用者在删除之前读取了容器记录，由它释放容器的网络资源
+		if _, err := KillContainer(containerId, syscall.SIGKILL, false); err != nil {
 			return errors.Errorf("stop a running container failed: %v", err)
 		}
 		RmContainer(containerId, opts)
@@ -3,6 +3,7 @@ package container
 import (
 	"os"
 	"os/exec"
+	"path"
 	"strings"
 
 	"github.com/DeJeune/sudocker/runtime/utils"
@@ -76,6 +77,7 @@ func createLower(containerId, imageName string) error {
 }
 
 // createDirs 创建overlayfs需要的的merged、upper、worker目录
+// 重新启动已停止的容器时这些目录已经存在，upper目录中的修改会被保留
 func createDirs(containerId string) error {
 	dirs := []string{
 		utils.GetMerged(containerId),
@@ -83,7 +85,7 @@ func createDirs(containerId string) error {
 		utils.GetWorker(containerId),
 	}
 	for _, dir := range dirs {
-		if err := os.Mkdir(dir, 0o777); err != nil {
+		if err := os.MkdirAll(dir, 0o777); err != nil {
 			return errors.Errorf("mkdir dir %s error. %v", dir, err)
 		}
 	}
@@ -95,6 +97,10 @@ func mountOverlayFS(containerId string) error {
 	// mount -t overlay overlay -o lowerdir=lower1:lower2:lower3,upperdir=upper,workdir=work merged
 	dirs := utils.GetOverlayFSDirs(utils.GetLower(containerId), utils.GetUpper(containerId), utils.GetWorker(containerId))
 	mergedPath := utils.GetMerged(containerId)
+	// 容器停止后merged目录仍处于挂载状态，无需重复挂载
+	if ok, err := mounted(mergedPath); err != nil || ok {
+		return err
+	}
 	if err := mount("overlay", mergedPath, "overlay", 0, dirs); err != nil {
 		return errors.Errorf("%v", err)
 	}
@@ -110,6 +116,10 @@ func DeleteStorageDriver(containerId string, volumes []string) error {
 				return errors.Errorf("extract volume failed, maybe volume parameter input is not correct, detail: %v", err)
 			}
 			mntPath := utils.GetMerged(containerId)
+			// 创建失败或者已经被清理的容器没有挂载volume
+			if ok, _ := mounted(path.Join(mntPath, containerPath)); !ok {
+				continue
+			}
 			if err := umountVolume(mntPath, containerPath); err != nil {
 				return errors.Errorf("umount volume failed: %v", err)
 			}
@@ -126,8 +136,10 @@ func DeleteStorageDriver(containerId string, volumes []string) error {
 
 func umountOverlayFS(containerId string) error {
 	mntPath := utils.GetMerged(containerId)
-	if err := unmount(mntPath, 0); err != nil {
-		return errors.Errorf("umount failed %v", err)
+	if ok, _ := mounted(mntPath); ok {
+		if err := unmount(mntPath, 0); err != nil {
+			return errors.Errorf("umount failed %v", err)
+		}
 	}
 	if err := os.RemoveAll(mntPath); err != nil {
 		return errors.Errorf("Remove dir %s error %v", mntPath, err)
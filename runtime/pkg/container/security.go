@@ -0,0 +1,101 @@
+package container
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/DeJeune/sudocker/runtime/pkg/seccomp"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// --security-opt 支持的选项
+const (
+	SecurityOptNoNewPrivileges = "no-new-privileges"
+	SecurityOptSeccomp         = "seccomp"
+	SecurityOptLabel           = "label"
+)
+
+const (
+	// SeccompUnconfined 表示 --security-opt seccomp=unconfined，不过滤系统调用
+	SeccompUnconfined = "unconfined"
+	// labelDisable 是唯一支持的label选项，sudocker不设置SELinux标签，它只为兼容 docker 的命令行而保留
+	labelDisable = "disable"
+)
+
+// SecurityOptions 是解析后的 --security-opt
+type SecurityOptions struct {
+	// NoNewPrivileges 为true时设置 PR_SET_NO_NEW_PRIVS，setuid程序无法获得更多的权限。
+	// 与 docker 一致默认为false，加载seccomp过滤器并不依赖它
+	NoNewPrivileges bool
+	// Seccomp 是seccomp配置的内容或者 SeccompUnconfined，为空时使用内置的默认配置
+	Seccomp       string
+	LabelDisabled bool
+}
+
+// SplitSecurityOpt 将一个 --security-opt 拆分为选项和值，并校验选项。
+// 与 docker 一致，同时接受 key=value 和旧的 key:value 写法，no-new-privileges 可以省略值
+func SplitSecurityOpt(opt string) (string, string, error) {
+	key, value, ok := strings.Cut(opt, "=")
+	if !ok && key != SecurityOptNoNewPrivileges {
+		key, value, ok = strings.Cut(opt, ":")
+	}
+	switch key {
+	case SecurityOptNoNewPrivileges:
+		if !ok {
+			return key, "true", nil
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "", "", errors.Errorf("invalid --security-opt %q: no-new-privileges must be true or false", opt)
+		}
+	case SecurityOptSeccomp:
+		if value == "" {
+			return "", "", errors.Errorf("invalid --security-opt %q: seccomp needs a profile file or %q", opt, SeccompUnconfined)
+		}
+	case SecurityOptLabel:
+		if value != labelDisable {
+			return "", "", errors.Errorf("invalid --security-opt %q: only label=disable is supported, sudocker does not apply SELinux labels", opt)
+		}
+	default:
+		return "", "", errors.Errorf("invalid --security-opt %q: unknown option %q, supported options are no-new-privileges[:true|false], seccomp=<profile>|unconfined and label=disable", opt, key)
+	}
+	return key, value, nil
+}
+
+// ParseSecurityOpts 解析容器中保存的 --security-opt，同一个选项出现多次时以最后一个为准
+func ParseSecurityOpts(opts []string) (*SecurityOptions, error) {
+	options := &SecurityOptions{}
+	for _, opt := range opts {
+		key, value, err := SplitSecurityOpt(opt)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case SecurityOptNoNewPrivileges:
+			options.NoNewPrivileges, _ = strconv.ParseBool(value)
+		case SecurityOptSeccomp:
+			options.Seccomp = value
+		case SecurityOptLabel:
+			options.LabelDisabled = true
+		}
+	}
+	return options, nil
+}
+
+// SeccompFilter 编译进程的seccomp过滤器，caps是进程的capabilities。
+// 没有指定seccomp配置时使用内置的默认配置；特权的进程和 seccomp=unconfined 返回nil
+func (o *SecurityOptions) SeccompFilter(privileged bool, caps []string) ([]unix.SockFilter, error) {
+	if privileged || o.Seccomp == SeccompUnconfined {
+		return nil, nil
+	}
+	profile := seccomp.DefaultProfile()
+	if o.Seccomp != "" {
+		// 创建容器时已经读入了配置文件的内容
+		p, err := seccomp.ParseProfile([]byte(o.Seccomp))
+		if err != nil {
+			return nil, err
+		}
+		profile = p
+	}
+	return seccomp.Compile(profile, caps)
+}
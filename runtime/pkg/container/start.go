@@ -0,0 +1,91 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/DeJeune/sudocker/runtime/pkg/system"
+	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/pkg/errors"
+)
+
+// StartContainer 启动一个处于Created状态的容器：打开容器的exec.fifo读端，
+// 解除init进程的阻塞，使其执行用户命令，并将容器状态修改为Running
+func StartContainer(ref string) error {
+	containerInfo, err := GetInfoByContainerId(ref)
+	if err != nil {
+		return errors.Errorf("Get container %s info error %v", ref, err)
+	}
+	containerId := containerInfo.Id
+	if containerInfo.Status != Created {
+		return errors.Errorf("container %s is %s, only created containers can be started", containerId, containerInfo.Status)
+	}
+	pid, err := strconv.Atoi(containerInfo.Pid)
+	if err != nil {
+		return errors.Errorf("Conver pid from string to int error %v", err)
+	}
+	fifoName := path.Join(fmt.Sprintf(utils.InfoLocFormat, containerId), utils.ExecFifoName)
+	if err := awaitFifoOpen(fifoName, pid); err != nil {
+		return errors.WithMessagef(err, "start container %s", containerId)
+	}
+	// 等待期间容器可能已经退出并被monitor进程记录，只有记录仍然是这次创建的init进程时才修改状态
+	_, err = UpdateContainerInfo(containerId, func(info *Info) error {
+		if info.Status != Created || info.Pid != strconv.Itoa(pid) {
+			return ErrSkipUpdate
+		}
+		info.Status = Running
+		info.StartedAt = time.Now().UTC()
+		return nil
+	})
+	return err
+}
+
+// awaitFifoOpen 打开并读取exec.fifo。如果init进程在此之前就已经退出，打开fifo会一直阻塞，
+// 因此在等待的同时轮询init进程是否存活
+func awaitFifoOpen(fifoName string, pid int) error {
+	result := make(chan error, 1)
+	go func() {
+		f, err := os.OpenFile(fifoName, os.O_RDONLY, 0)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			result <- err
+			return
+		}
+		if len(data) == 0 {
+			result <- errors.New("container init exited before the exec fifo was written")
+			return
+		}
+		result <- os.Remove(fifoName)
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-result:
+			return err
+		case <-ticker.C:
+			if !IsProcessAlive(pid) {
+				return errors.Errorf("container init process %d is not running", pid)
+			}
+		}
+	}
+}
+
+// IsProcessAlive 判断pid对应的进程是否存在且不是僵尸进程
+func IsProcessAlive(pid int) bool {
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return false
+	}
+	return stat.State != system.Zombie && stat.State != system.Dead
+}
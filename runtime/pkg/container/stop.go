@@ -1,54 +1,95 @@
 package container
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path"
 	"strconv"
 	"syscall"
+	"time"
 
-	"github.com/DeJeune/sudocker/runtime/utils"
+	"github.com/moby/sys/signal"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
-func StopContainer(containerId string) error {
-	containerInfo, err := GetInfoByContainerId(containerId)
+// DefaultStopTimeout 是容器没有设置 --stop-timeout 时，stop 等待容器退出的秒数
+const DefaultStopTimeout = 10
+
+// StopContainer 向容器发送停止信号（默认SIGTERM，可通过 --stop-signal 指定），等待容器退出，
+// 超时后使用SIGKILL强制结束。timeout为nil时使用容器自身的stop timeout。
+// init进程已经不存在时返回修正之前的容器信息，调用者据此释放容器的网络资源
+func StopContainer(ref string, timeout *int) (*Info, error) {
+	containerInfo, err := GetInfoByContainerId(ref)
 	if err != nil {
-		return errors.Errorf("Get container %s info error %v", containerId, err)
+		return nil, errors.Errorf("Get container %s info error %v", ref, err)
+	}
+	containerId := containerInfo.Id
+	if containerInfo.Status != Running && containerInfo.Status != Paused {
+		return nil, nil
+	}
+	// init进程已经退出，或者pid已经被其他进程复用，不能再向它发送信号
+	if !InitProcessAlive(containerInfo) {
+		return ReconcileContainer(containerId)
 	}
 	pidInt, err := strconv.Atoi(containerInfo.Pid)
 	if err != nil {
-		return errors.Errorf("Conver pid from string to int error %v", err)
+		return nil, errors.Errorf("Conver pid from string to int error %v", err)
 	}
-	if err := syscall.Kill(pidInt, syscall.SIGTERM); err != nil {
-		return errors.Errorf("Stop container %s error %v", containerId, err)
-	}
-	// 修改容器信息
-	containerInfo.Status = Stopped
-	containerInfo.Pid = ""
-	newContentBytes, err := json.Marshal(containerInfo)
+	stopSignal, err := containerStopSignal(containerInfo)
 	if err != nil {
-		return errors.Errorf("Json marshal %s error %v", containerId, err)
+		return nil, err
 	}
-	dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
-	configFilePath := path.Join(dirPath, utils.ConfigName)
-	if err = os.WriteFile(configFilePath, newContentBytes, 0o622); err != nil {
-		return errors.Errorf("Write file %s error:%v", configFilePath, err)
+	stopTimeout := containerStopTimeout(containerInfo)
+	if timeout != nil {
+		stopTimeout = *timeout
 	}
-	return nil
+
+	// 先记录手动停止的标记，避免monitor进程在容器退出后按照重启策略将其重启
+	if _, err := UpdateContainerInfo(containerId, func(info *Info) error {
+		info.ManuallyStopped = true
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	exitSignal := stopSignal
+	if err := syscall.Kill(pidInt, stopSignal); err != nil && err != syscall.ESRCH {
+		return nil, errors.Errorf("Stop container %s error %v", containerId, err)
+	}
+	// 暂停中的容器需要解冻后才能处理信号
+	if err := thawPaused(containerInfo); err != nil {
+		return nil, err
+	}
+	if !waitForExit(pidInt, time.Duration(stopTimeout)*time.Second) {
+		logrus.Infof("Container %s failed to exit within %d seconds of signal %d - using the force", containerId, stopTimeout, stopSignal)
+		exitSignal = syscall.SIGKILL
+		if err := syscall.Kill(pidInt, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			return nil, errors.Errorf("Kill container %s error %v", containerId, err)
+		}
+		if !waitForExit(pidInt, 10*time.Second) {
+			return nil, errors.Errorf("Container %s did not exit after SIGKILL", containerId)
+		}
+	}
+	// 修改容器信息
+	// stop 不是容器init进程的父进程，拿不到真实的退出状态，按照惯例记录为 128+信号
+	// 容器由monitor进程看护时，monitor也会记录它拿到的真实退出码
+	return nil, recordSignalExit(containerId, containerInfo.Pid, exitSignal)
 }
 
-func GetInfoByContainerId(containerId string) (*Info, error) {
-	dirPath := fmt.Sprintf(utils.InfoLocFormat, containerId)
-	configFilePath := path.Join(dirPath, utils.ConfigName)
-	contentBytes, err := os.ReadFile(configFilePath)
+// containerStopSignal 返回容器的停止信号，未设置时为SIGTERM
+func containerStopSignal(info *Info) (syscall.Signal, error) {
+	if info.State.Config.StopSignal == "" {
+		return syscall.SIGTERM, nil
+	}
+	sig, err := signal.ParseSignal(info.State.Config.StopSignal)
 	if err != nil {
-		return nil, errors.Wrapf(err, "read file %s", configFilePath)
+		return -1, errors.WithMessagef(err, "container %s", info.Id)
 	}
-	var containerInfo Info
-	if err = json.Unmarshal(contentBytes, &containerInfo); err != nil {
-		return nil, err
+	return sig, nil
+}
+
+// containerStopTimeout 返回容器的停止超时时间（秒）
+func containerStopTimeout(info *Info) int {
+	if info.State.Config.StopTimeout != nil {
+		return *info.State.Config.StopTimeout
 	}
-	return &containerInfo, nil
+	return DefaultStopTimeout
 }
@@ -0,0 +1,71 @@
+package container
+
+import (
+	"syscall"
+
+	"github.com/DeJeune/sudocker/runtime/pkg/capabilites"
+	"github.com/DeJeune/sudocker/runtime/pkg/system"
+	"github.com/DeJeune/sudocker/runtime/pkg/user"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/pkg/errors"
+)
+
+// ResolveUser 在容器rootfs的 /etc/passwd 和 /etc/group 中解析 --user 和 --group-add 指定的用户和附加组。
+// 没有指定用户时为root；rootfs中没有这两个文件时只能使用数字形式的uid和gid
+func ResolveUser(rootfs, spec string, groupAdd []string) (*user.ExecUser, error) {
+	passwdPath, err := securejoin.SecureJoin(rootfs, "/etc/passwd")
+	if err != nil {
+		return nil, err
+	}
+	groupPath, err := securejoin.SecureJoin(rootfs, "/etc/group")
+	if err != nil {
+		return nil, err
+	}
+	execUser, err := user.GetExecUserPath(spec, &user.ExecUser{Home: "/"}, passwdPath, groupPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupAdd) > 0 {
+		gids, err := user.GetAdditionalGroupsPath(groupAdd, groupPath)
+		if err != nil {
+			return nil, err
+		}
+		execUser.Sgids = append(execUser.Sgids, gids...)
+	}
+	return execUser, nil
+}
+
+// setupUser 切换到解析得到的用户和组，syscall 中的实现对进程的所有线程生效
+func setupUser(execUser *user.ExecUser) error {
+	if err := syscall.Setgroups(execUser.Sgids); err != nil {
+		return errors.WithMessage(err, "setgroups")
+	}
+	if err := syscall.Setgid(execUser.Gid); err != nil {
+		return errors.WithMessagef(err, "setgid %d", execUser.Gid)
+	}
+	if err := syscall.Setuid(execUser.Uid); err != nil {
+		return errors.WithMessagef(err, "setuid %d", execUser.Uid)
+	}
+	return nil
+}
+
+// finalizeProcess 按照 runc 的顺序设置capabilities并切换用户：先收紧bounding set，
+// 切换用户期间保留permitted的capabilities，切换之后再设置全部capabilities
+func finalizeProcess(caps *capabilites.Caps, execUser *user.ExecUser) error {
+	if err := system.SetKeepCaps(); err != nil {
+		return errors.WithMessage(err, "set keep caps")
+	}
+	if err := caps.ApplyBoundingSet(); err != nil {
+		return errors.WithMessage(err, "apply bounding set")
+	}
+	if err := setupUser(execUser); err != nil {
+		return err
+	}
+	if err := system.ClearKeepCaps(); err != nil {
+		return errors.WithMessage(err, "clear keep caps")
+	}
+	if err := caps.ApplyCaps(); err != nil {
+		return errors.WithMessage(err, "apply capabilities")
+	}
+	return nil
+}
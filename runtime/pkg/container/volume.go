@@ -21,6 +21,10 @@ func mountVolume(mntPath, hostPath, containerPath string) error {
 	if err := os.MkdirAll(containerPathInHost, 0o777); err != nil {
 		return errors.Errorf("mkdir container dir %s error. %v", containerPathInHost, err)
 	}
+	// 重新启动已停止的容器时volume已经挂载过了
+	if ok, err := mounted(containerPathInHost); err != nil || ok {
+		return err
+	}
 	// 通过bind mount 将宿主机目录挂载到容器目录
 	// mount -o bind /hostPath /containerPath
 
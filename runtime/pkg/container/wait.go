@@ -0,0 +1,108 @@
+package container
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WaitCondition 是 sudocker wait 等待的条件
+type WaitCondition string
+
+const (
+	// WaitConditionNotRunning 等待容器不在运行，容器已经停止时立即返回
+	WaitConditionNotRunning WaitCondition = "not-running"
+	// WaitConditionNextExit 等待容器的下一次退出
+	WaitConditionNextExit WaitCondition = "next-exit"
+	// WaitConditionRemoved 等待容器被删除
+	WaitConditionRemoved WaitCondition = "removed"
+)
+
+const (
+	waitPollInterval    = 100 * time.Millisecond
+	unrecordedExitGrace = time.Second
+)
+
+// WaitContainer 阻塞直到容器满足condition，返回容器最近一次的退出码
+func WaitContainer(ctx context.Context, containerId string, condition WaitCondition) (int, error) {
+	switch condition {
+	case WaitConditionNotRunning, WaitConditionNextExit, WaitConditionRemoved:
+	default:
+		return -1, errors.Errorf("invalid condition: %q", condition)
+	}
+	info, err := GetInfoByContainerId(containerId)
+	if err != nil {
+		return -1, errors.Errorf("Get container %s info error %v", containerId, err)
+	}
+	return waitContainer(ctx, info, condition, info.FinishedAt)
+}
+
+// WaitExitSince 等待容器在since之后退出，返回退出码。用于在启动容器之前确定等待的起点
+func WaitExitSince(ctx context.Context, containerId string, since time.Time) (int, error) {
+	info, err := GetInfoByContainerId(containerId)
+	if err != nil {
+		return -1, errors.Errorf("Get container %s info error %v", containerId, err)
+	}
+	return waitContainer(ctx, info, WaitConditionNextExit, since)
+}
+
+func waitContainer(ctx context.Context, info *Info, condition WaitCondition, since time.Time) (int, error) {
+	containerId := info.Id
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	var exitedAt time.Time
+	for {
+		// init进程退出后，给它的父进程（如前台运行的 sudocker run）留出记录退出状态的时间，
+		// 超时仍没有记录时认为容器已经退出
+		exited := false
+		if exitedUnrecorded(info) {
+			if exitedAt.IsZero() {
+				exitedAt = time.Now()
+			}
+			exited = time.Since(exitedAt) >= unrecordedExitGrace
+		}
+		switch condition {
+		case WaitConditionNotRunning:
+			if exited || info.Status != Running && info.Status != Paused {
+				return info.ExitCode, nil
+			}
+		case WaitConditionNextExit:
+			if exited || info.FinishedAt.After(since) {
+				return info.ExitCode, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-ticker.C:
+		}
+
+		next, err := readContainerInfo(containerId)
+		if err != nil {
+			// 容器已经被删除，返回最后一次记录的退出码
+			if errors.Is(err, os.ErrNotExist) {
+				return info.ExitCode, nil
+			}
+			return -1, errors.Errorf("Get container %s info error %v", containerId, err)
+		}
+		if next.Pid != info.Pid {
+			exitedAt = time.Time{}
+		}
+		info = next
+	}
+}
+
+// exitedUnrecorded 判断容器的init进程是否已经退出但记录仍然是运行中。
+// 旧版本后台启动的容器没有monitor进程看护，退出后没有进程会更新它的记录
+func exitedUnrecorded(info *Info) bool {
+	if info.Status != Running && info.Status != Paused {
+		return false
+	}
+	if info.Monitored() && IsProcessAlive(info.MonitorPid) {
+		return false
+	}
+	return !InitProcessAlive(info)
+}
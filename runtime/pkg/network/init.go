@@ -59,6 +59,19 @@ func loadNetwork() (map[string]*config.Network, error) {
 	return networks, err
 }
 
+// GetNetwork 根据名字获取 Network，不存在时返回的错误满足 os.IsNotExist
+func GetNetwork(name string) (*config.Network, error) {
+	networks, err := loadNetwork()
+	if err != nil {
+		return nil, errors.Errorf("load network from file failed,detail: %v", err)
+	}
+	net, ok := networks[name]
+	if !ok {
+		return nil, errors.Wrapf(os.ErrNotExist, "no such network: %s", name)
+	}
+	return net, nil
+}
+
 // CreateNetwork 根据不同 driver 创建 Network
 func CreateNetwork(driver, subnet, name string) error {
 	// 将网段的字符串转换成net. IPNet的对象
@@ -174,6 +187,36 @@ func Connect(networkName string, info *container.Info) (net.IP, error) {
 	return ip, addPortMapping(ep)
 }
 
+// Disconnect 将容器从网络中断开：删除端口映射规则和veth设备，并释放容器的IP地址
+func Disconnect(networkName string, info *container.Info) error {
+	network, err := GetNetwork(networkName)
+	if err != nil {
+		return err
+	}
+	ep := &config.Endpoint{
+		Uuid:    fmt.Sprintf("%s-%s", info.Id, networkName),
+		Network: network,
+		Ports:   info.PortMapping,
+	}
+	if ip := net.ParseIP(info.IP).To4(); ip != nil {
+		ep.IPAddr = ip
+		if err := configPortMapping(ep, true); err != nil {
+			logrus.Warnf("delete port mapping of container %s: %v", info.Id, err)
+		}
+		// Release 会修改传入的IP，使用一份拷贝
+		releaseIP := append(net.IP{}, ip...)
+		if err := ipAllocator.Release(network.SubNet, &releaseIP); err != nil {
+			return errors.WithMessagef(err, "release ip %s", info.IP)
+		}
+	}
+	// 容器的network namespace销毁时veth设备通常已经被内核删除
+	var notFound netlink.LinkNotFoundError
+	if err := drivers[network.Bridge].Disconnect(ep.Uuid); err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+	return nil
+}
+
 func addPortMapping(ep *config.Endpoint) error {
 	return configPortMapping(ep, false)
 }
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 )
 
 const ipamDefaultAllocatorPath = "/var/lib/sudocker/network/ipam/subnet.json"
@@ -24,6 +25,11 @@ var ipAllocator = &IPAM{
 
 // 在网段分配一个keys的ip地址
 func (ipam *IPAM) Allocate(subnet *net.IPNet) (ip net.IP, err error) {
+	unlock, err := ipam.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	// 存放网段中地址分配信息的数组
 	ipam.Subnets = &map[string]string{}
 	err = ipam.load()
@@ -78,10 +84,15 @@ func (ipam *IPAM) Allocate(subnet *net.IPNet) (ip net.IP, err error) {
 }
 
 func (ipam *IPAM) Release(subnet *net.IPNet, ipaddr *net.IP) error {
+	unlock, err := ipam.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	ipam.Subnets = &map[string]string{}
 	_, subnet, _ = net.ParseCIDR(subnet.String())
 
-	err := ipam.load()
+	err = ipam.load()
 	if err != nil {
 		return errors.Wrap(err, "load subnet allocation info error")
 	}
@@ -148,3 +159,24 @@ func (ipam *IPAM) dump() error {
 
 	return encoder.Encode(ipam.Subnets)
 }
+
+// lock 对分配文件所在的目录加flock排他锁，返回解锁函数。
+// 多个容器的monitor进程会同时分配、释放IP，加锁避免分配信息被互相覆盖
+func (ipam *IPAM) lock() (func(), error) {
+	ipamConfigFileDir, _ := path.Split(ipam.SubnetAllocatorPath)
+	if err := os.MkdirAll(ipamConfigFileDir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "mkdir %s", ipamConfigFileDir)
+	}
+	dir, err := os.Open(ipamConfigFileDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", ipamConfigFileDir)
+	}
+	if err := unix.Flock(int(dir.Fd()), unix.LOCK_EX); err != nil {
+		_ = dir.Close()
+		return nil, errors.Wrapf(err, "lock %s", ipamConfigFileDir)
+	}
+	return func() {
+		_ = unix.Flock(int(dir.Fd()), unix.LOCK_UN)
+		_ = dir.Close()
+	}, nil
+}
@@ -9,6 +9,97 @@ package nsenter
 #include <stdlib.h>
 #include <string.h>
 #include <fcntl.h>
+#include <grp.h>
+#include <sys/types.h>
+#include <sys/prctl.h>
+#include <sys/syscall.h>
+#include <linux/capability.h>
+#include <linux/filter.h>
+#include <linux/seccomp.h>
+
+// set_caps 将当前进程的permitted和effective设置为mask，当前进程没有的capabilities无法再获得
+static int set_caps(unsigned long long mask) {
+	struct __user_cap_header_struct hdr = { _LINUX_CAPABILITY_VERSION_3, 0 };
+	struct __user_cap_data_struct data[2];
+	memset(data, 0, sizeof(data));
+	if (syscall(SYS_capget, &hdr, data) == -1) {
+		return -1;
+	}
+	for (int i = 0; i < 2; i++) {
+		data[i].permitted &= (__u32)(mask >> (32 * i));
+		data[i].effective = data[i].permitted;
+		data[i].inheritable = 0;
+	}
+	return syscall(SYS_capset, &hdr, data);
+}
+
+// load_seccomp 解析 sudocker exec 编码的BPF程序并加载为seccomp过滤器，每条指令是16个十六进制字符：code、jt、jf、k。
+// 没有设置no_new_privs时需要拥有 CAP_SYS_ADMIN
+static int load_seccomp(const char *encoded) {
+	size_t len = strlen(encoded);
+	if (len == 0 || len % 16 != 0 || len / 16 > BPF_MAXINSNS) {
+		errno = EINVAL;
+		return -1;
+	}
+	unsigned short n = len / 16;
+	struct sock_filter *filter = calloc(n, sizeof(struct sock_filter));
+	if (!filter) {
+		return -1;
+	}
+	for (unsigned short i = 0; i < n; i++) {
+		unsigned int code, jt, jf, k;
+		if (sscanf(encoded + 16 * i, "%4x%2x%2x%8x", &code, &jt, &jf, &k) != 4) {
+			free(filter);
+			errno = EINVAL;
+			return -1;
+		}
+		filter[i].code = code;
+		filter[i].jt = jt;
+		filter[i].jf = jf;
+		filter[i].k = k;
+	}
+	struct sock_fprog prog = { .len = n, .filter = filter };
+	int ret = prctl(PR_SET_SECCOMP, SECCOMP_MODE_FILTER, &prog);
+	free(filter);
+	return ret;
+}
+
+// decode_args 解析 sudocker exec 编码的命令，每个参数编码为十六进制字符串，参数之间以逗号分隔。
+// 参数原样传给execvp，不经过shell解析，其中的空格和引号不会被拆分
+static char **decode_args(const char *encoded) {
+	size_t n = 1;
+	for (const char *c = encoded; *c; c++) {
+		if (*c == ',') {
+			n++;
+		}
+	}
+	char **argv = calloc(n + 1, sizeof(char *));
+	if (!argv) {
+		return NULL;
+	}
+	const char *p = encoded;
+	for (size_t i = 0; i < n; i++) {
+		size_t len = strcspn(p, ",");
+		if (len % 2 != 0) {
+			errno = EINVAL;
+			return NULL;
+		}
+		argv[i] = calloc(len / 2 + 1, 1);
+		if (!argv[i]) {
+			return NULL;
+		}
+		for (size_t j = 0; j < len / 2; j++) {
+			unsigned int byte;
+			if (sscanf(p + 2 * j, "%2x", &byte) != 1) {
+				errno = EINVAL;
+				return NULL;
+			}
+			argv[i][j] = (char)byte;
+		}
+		p += len + 1;
+	}
+	return argv;
+}
 
 __attribute__((constructor)) void enter_namespace(void) {
    // 这里的代码会在Go运行时启动前执行，它会在单线程的C上下文中运行
@@ -47,10 +138,105 @@ __attribute__((constructor)) void enter_namespace(void) {
 		}
 		close(fd);
 	}
-	// 在进入的Namespace中执行指定命令，然后退出
-	int res = system(mydocker_cmd);
-	exit(0);
-	return;
+	// 进入容器中的工作目录，setns进入mount namespace后当前目录位于容器的根目录
+	char *mydocker_cwd = getenv("mydocker_cwd");
+	if (mydocker_cwd && chdir(mydocker_cwd) == -1) {
+		fprintf(stderr, "chdir to %s failed: %s\n", mydocker_cwd, strerror(errno));
+		exit(1);
+	}
+	// 按照 sudocker exec 计算的capabilities收紧bounding set，切换用户期间保留permitted的capabilities
+	char *mydocker_caps = getenv("mydocker_caps");
+	unsigned long long caps = 0;
+	if (mydocker_caps) {
+		caps = strtoull(mydocker_caps, NULL, 16);
+		for (int c = 0; c < 64; c++) {
+			if (!(caps & (1ULL << c)) && prctl(PR_CAPBSET_DROP, c, 0, 0, 0) == -1 && errno != EINVAL) {
+				fprintf(stderr, "drop capability %d failed: %s\n", c, strerror(errno));
+				exit(1);
+			}
+		}
+		prctl(PR_SET_KEEPCAPS, 1, 0, 0, 0);
+	}
+	// 没有设置no_new_privs时，加载seccomp过滤器需要 CAP_SYS_ADMIN，因此在切换用户和收紧capabilities之前加载
+	char *mydocker_seccomp = getenv("mydocker_seccomp");
+	char *filter = mydocker_seccomp ? strdup(mydocker_seccomp) : NULL;
+	int no_new_privs = getenv("mydocker_no_new_privs") != NULL;
+	if (filter && !no_new_privs) {
+		if (load_seccomp(filter) == -1) {
+			fprintf(stderr, "load seccomp filter failed: %s\n", strerror(errno));
+			exit(1);
+		}
+		free(filter);
+		filter = NULL;
+	}
+	// 切换到 sudocker exec 在容器中解析得到的用户，先设置附加组和gid，最后设置uid
+	char *mydocker_groups = getenv("mydocker_groups");
+	if (mydocker_groups) {
+		int size = 1;
+		for (char *c = mydocker_groups; *c; c++) {
+			if (*c == ',') {
+				size++;
+			}
+		}
+		gid_t *groups = calloc(size, sizeof(gid_t));
+		int n = 0;
+		char *list = strdup(mydocker_groups);
+		char *saveptr = NULL;
+		for (char *tok = strtok_r(list, ",", &saveptr); tok && n < size; tok = strtok_r(NULL, ",", &saveptr)) {
+			groups[n++] = (gid_t)atoi(tok);
+		}
+		free(list);
+		if (setgroups(n, groups) == -1) {
+			fprintf(stderr, "setgroups failed: %s\n", strerror(errno));
+			exit(1);
+		}
+		free(groups);
+	}
+	char *mydocker_gid = getenv("mydocker_gid");
+	if (mydocker_gid && setgid((gid_t)atoi(mydocker_gid)) == -1) {
+		fprintf(stderr, "setgid %s failed: %s\n", mydocker_gid, strerror(errno));
+		exit(1);
+	}
+	char *mydocker_uid = getenv("mydocker_uid");
+	if (mydocker_uid && setuid((uid_t)atoi(mydocker_uid)) == -1) {
+		fprintf(stderr, "setuid %s failed: %s\n", mydocker_uid, strerror(errno));
+		exit(1);
+	}
+	if (mydocker_caps) {
+		prctl(PR_SET_KEEPCAPS, 0, 0, 0, 0);
+		if (set_caps(caps) == -1) {
+			fprintf(stderr, "set capabilities failed: %s\n", strerror(errno));
+			exit(1);
+		}
+	}
+	// 删除nsenter使用的变量，命令只看到容器的环境变量
+	char **argv = decode_args(mydocker_cmd);
+	if (!argv || !argv[0] || !argv[0][0]) {
+		fprintf(stderr, "invalid command %s\n", mydocker_cmd);
+		exit(1);
+	}
+	char *envs[] = { "mydocker_pid", "mydocker_cmd", "mydocker_cwd", "mydocker_uid", "mydocker_gid", "mydocker_groups", "mydocker_caps", "mydocker_seccomp", "mydocker_no_new_privs" };
+	for (i=0; i<9; i++) {
+		unsetenv(envs[i]);
+	}
+	// 设置了no_new_privs时最后加载seccomp过滤器，之前的步骤不受容器的seccomp配置限制
+	if (no_new_privs && prctl(PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0) == -1) {
+		fprintf(stderr, "set no_new_privs failed: %s\n", strerror(errno));
+		exit(1);
+	}
+	if (filter) {
+		if (load_seccomp(filter) == -1) {
+			fprintf(stderr, "load seccomp filter failed: %s\n", strerror(errno));
+			exit(1);
+		}
+		free(filter);
+	}
+	// 在进入的Namespace中执行指定命令，命令的退出码即为 sudocker exec 的退出码。
+	// 与 docker 一致，找不到命令时以127退出，无法执行时以126退出
+	fflush(stdout);
+	execvp(argv[0], argv);
+	fprintf(stderr, "exec %s failed: %s\n", argv[0], strerror(errno));
+	exit(errno == ENOENT ? 127 : 126);
 }
 */
 import "C"
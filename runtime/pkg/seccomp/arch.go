@@ -0,0 +1,180 @@
+package seccomp
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+//go:generate go run mksyscalls.go
+
+// Arch 是配置中的架构名称，例如 SCMP_ARCH_X86_64
+type Arch string
+
+const (
+	ArchX86         Arch = "SCMP_ARCH_X86"
+	ArchX86_64      Arch = "SCMP_ARCH_X86_64"
+	ArchX32         Arch = "SCMP_ARCH_X32"
+	ArchARM         Arch = "SCMP_ARCH_ARM"
+	ArchAARCH64     Arch = "SCMP_ARCH_AARCH64"
+	ArchMIPS        Arch = "SCMP_ARCH_MIPS"
+	ArchMIPS64      Arch = "SCMP_ARCH_MIPS64"
+	ArchMIPS64N32   Arch = "SCMP_ARCH_MIPS64N32"
+	ArchMIPSEL      Arch = "SCMP_ARCH_MIPSEL"
+	ArchMIPSEL64    Arch = "SCMP_ARCH_MIPSEL64"
+	ArchMIPSEL64N32 Arch = "SCMP_ARCH_MIPSEL64N32"
+	ArchPPC         Arch = "SCMP_ARCH_PPC"
+	ArchPPC64       Arch = "SCMP_ARCH_PPC64"
+	ArchPPC64LE     Arch = "SCMP_ARCH_PPC64LE"
+	ArchS390        Arch = "SCMP_ARCH_S390"
+	ArchS390X       Arch = "SCMP_ARCH_S390X"
+	ArchPARISC      Arch = "SCMP_ARCH_PARISC"
+	ArchPARISC64    Arch = "SCMP_ARCH_PARISC64"
+	ArchRISCV64     Arch = "SCMP_ARCH_RISCV64"
+	ArchLOONGARCH64 Arch = "SCMP_ARCH_LOONGARCH64"
+)
+
+// x32SyscallBit 是x32 ABI的系统调用号中设置的位，x32的系统调用同样以 AUDIT_ARCH_X86_64 进入内核
+const x32SyscallBit = 0x40000000
+
+// archInfo 描述可以编译过滤器的架构
+type archInfo struct {
+	audit uint32
+	// is32Bit 的架构只比较参数的低32位
+	is32Bit  bool
+	syscalls map[string]int
+}
+
+// supportedArches 是有系统调用表、可以编译过滤器的架构，其他架构的规则被忽略
+var supportedArches = map[Arch]*archInfo{
+	ArchX86_64:  {audit: unix.AUDIT_ARCH_X86_64, syscalls: syscallsX86_64},
+	ArchX86:     {audit: unix.AUDIT_ARCH_I386, is32Bit: true, syscalls: syscallsX86},
+	ArchAARCH64: {audit: unix.AUDIT_ARCH_AARCH64, syscalls: syscallsAARCH64},
+	ArchARM:     {audit: unix.AUDIT_ARCH_ARM, is32Bit: true, syscalls: syscallsARM},
+}
+
+// knownArches 是配置中可以出现的所有架构
+var knownArches = map[Arch]struct{}{
+	ArchX86: {}, ArchX86_64: {}, ArchX32: {}, ArchARM: {}, ArchAARCH64: {},
+	ArchMIPS: {}, ArchMIPS64: {}, ArchMIPS64N32: {}, ArchMIPSEL: {}, ArchMIPSEL64: {}, ArchMIPSEL64N32: {},
+	ArchPPC: {}, ArchPPC64: {}, ArchPPC64LE: {}, ArchS390: {}, ArchS390X: {},
+	ArchPARISC: {}, ArchPARISC64: {}, ArchRISCV64: {}, ArchLOONGARCH64: {},
+}
+
+// nativeArches 将GOARCH转换为 includes/excludes 中使用的架构名称，以及对应的seccomp架构
+var nativeArches = map[string]struct {
+	name string
+	arch Arch
+}{
+	"386":   {"x86", ArchX86},
+	"amd64": {"amd64", ArchX86_64},
+	"arm":   {"arm", ArchARM},
+	"arm64": {"arm64", ArchAARCH64},
+}
+
+// KernelVersion 是内核的主次版本号
+type KernelVersion struct {
+	Kernel uint64
+	Major  uint64
+}
+
+func (k KernelVersion) less(other KernelVersion) bool {
+	return k.Kernel < other.Kernel || (k.Kernel == other.Kernel && k.Major < other.Major)
+}
+
+// parseKernelVersion 解析形如 4.8 或者 6.1.0-13-amd64 的内核版本
+func parseKernelVersion(s string) (KernelVersion, error) {
+	var k KernelVersion
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) < 2 {
+		return k, errors.Errorf("invalid kernel version %q", s)
+	}
+	minor := parts[1]
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i]
+	}
+	var err error
+	if k.Kernel, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return k, errors.Errorf("invalid kernel version %q", s)
+	}
+	if k.Major, err = strconv.ParseUint(minor, 10, 64); err != nil {
+		return k, errors.Errorf("invalid kernel version %q", s)
+	}
+	return k, nil
+}
+
+// currentKernel 返回当前运行的内核版本
+func currentKernel() (KernelVersion, error) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return KernelVersion{}, err
+	}
+	return parseKernelVersion(unix.ByteSliceToString(uts.Release[:]))
+}
+
+// target 是编译过滤器的环境，决定配置中哪些规则生效
+type target struct {
+	// goarch 是容器进程的GOARCH
+	goarch string
+	caps   []string
+	kernel KernelVersion
+}
+
+func currentTarget(caps []string) (*target, error) {
+	kernel, err := currentKernel()
+	if err != nil {
+		return nil, errors.Errorf("get kernel version error %v", err)
+	}
+	return &target{goarch: runtime.GOARCH, caps: caps, kernel: kernel}, nil
+}
+
+// arches 返回过滤器需要处理的架构：本机的架构，以及配置中列出的它的子架构
+func (p *Profile) arches(goarch string) ([]Arch, error) {
+	native, ok := nativeArches[goarch]
+	if !ok {
+		return nil, errors.Errorf("seccomp is not supported on %s", goarch)
+	}
+	listed := p.Architectures
+	for _, m := range p.ArchMap {
+		if m.Arch == native.arch {
+			listed = append([]Arch{m.Arch}, m.SubArches...)
+		}
+	}
+	if len(listed) == 0 {
+		// 配置没有限制架构时只允许本机的架构
+		return []Arch{native.arch}, nil
+	}
+	var arches []Arch
+	for _, a := range listed {
+		if _, ok := supportedArches[a]; ok && compatible(native.arch, a) {
+			arches = append(arches, a)
+		}
+	}
+	if !containsArch(arches, native.arch) {
+		return nil, errors.Errorf("seccomp profile does not support the native architecture %s", native.arch)
+	}
+	return arches, nil
+}
+
+// compatible 判断arch的程序能否在native上运行
+func compatible(native, arch Arch) bool {
+	switch native {
+	case ArchX86_64:
+		return arch == ArchX86_64 || arch == ArchX86
+	case ArchAARCH64:
+		return arch == ArchAARCH64 || arch == ArchARM
+	}
+	return arch == native
+}
+
+func containsArch(arches []Arch, arch Arch) bool {
+	for _, a := range arches {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package seccomp
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// 过滤器的返回值，见 linux/seccomp.h
+const (
+	retKillProcess uint32 = 0x80000000
+	retKillThread  uint32 = 0x00000000
+	retTrap        uint32 = 0x00030000
+	retErrno       uint32 = 0x00050000
+	retTrace       uint32 = 0x7ff00000
+	retLog         uint32 = 0x7ffc0000
+	retAllow       uint32 = 0x7fff0000
+	retDataMask    uint32 = 0x0000ffff
+)
+
+// struct seccomp_data 中各字段的偏移。支持的架构都是小端序，参数的低32位在前
+const (
+	offsetNr   = 0
+	offsetArch = 4
+	offsetArgs = 16
+)
+
+const (
+	// maxArgs 是系统调用参数的个数
+	maxArgs = 6
+	// maxInsns 是内核允许的BPF程序的最大长度
+	maxInsns = 4096
+	// badArchAction 是不在配置中的架构（包括x32）的系统调用的处理方式
+	badArchAction = retKillProcess
+	// failJump 是生成参数比较时表示“条件不满足”的跳转，在整个比较生成之后替换为实际的偏移
+	failJump = 0xff
+)
+
+// actionValue 将配置中的处理方式转换为过滤器的返回值，SCMP_ACT_ERRNO 和 SCMP_ACT_TRACE 默认返回 EPERM
+func actionValue(action Action, errnoRet *uint) (uint32, error) {
+	errno := uint32(unix.EPERM)
+	if errnoRet != nil {
+		if action != ActErrno && action != ActTrace {
+			return 0, errors.Errorf("errnoRet is only supported for %s and %s, not %s", ActErrno, ActTrace, action)
+		}
+		if *errnoRet > uint(retDataMask) {
+			return 0, errors.Errorf("errnoRet %d is out of range", *errnoRet)
+		}
+		errno = uint32(*errnoRet)
+	}
+	switch action {
+	case ActKill, ActKillThread:
+		return retKillThread, nil
+	case ActKillProcess:
+		return retKillProcess, nil
+	case ActTrap:
+		return retTrap, nil
+	case ActErrno:
+		return retErrno | errno, nil
+	case ActTrace:
+		return retTrace | errno, nil
+	case ActAllow:
+		return retAllow, nil
+	case ActLog:
+		return retLog, nil
+	}
+	return 0, errors.Errorf("unknown action %q", action)
+}
+
+// rule 是在当前环境中生效的一条规则，满足conditions中任意一组条件时按照action处理
+type rule struct {
+	names  []string
+	action uint32
+	// conditions 中每一组条件需要全部满足，没有参数条件时只有一个空的组
+	conditions [][]*Arg
+}
+
+// Compile 针对当前的内核和架构将配置编译为BPF程序，caps是容器进程的capabilities，
+// 决定配置中 includes 和 excludes 了capabilities的规则是否生效
+func Compile(p *Profile, caps []string) ([]unix.SockFilter, error) {
+	t, err := currentTarget(caps)
+	if err != nil {
+		return nil, err
+	}
+	return p.compile(t)
+}
+
+// compile 生成的程序先根据架构跳转到对应的部分，每个部分依次比较规则中的系统调用号和参数，
+// 第一条匹配的规则决定返回值，都不匹配时返回默认的处理方式
+func (p *Profile) compile(t *target) ([]unix.SockFilter, error) {
+	defaultAction, err := actionValue(p.DefaultAction, p.DefaultErrnoRet)
+	if err != nil {
+		return nil, err
+	}
+	arches, err := p.arches(t.goarch)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := p.rules(t, defaultAction)
+	if err != nil {
+		return nil, err
+	}
+
+	prog := []unix.SockFilter{load(offsetArch)}
+	jumps := make([]int, len(arches))
+	for i, arch := range arches {
+		prog = append(prog, jumpIf(unix.BPF_JEQ, supportedArches[arch].audit, 0, 1))
+		jumps[i] = len(prog)
+		prog = append(prog, unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JA})
+	}
+	prog = append(prog, ret(badArchAction))
+	for i, arch := range arches {
+		prog[jumps[i]].K = uint32(len(prog) - jumps[i] - 1)
+		section, err := compileArch(arch, rules, defaultAction)
+		if err != nil {
+			return nil, err
+		}
+		prog = append(prog, section...)
+	}
+	if len(prog) > maxInsns {
+		return nil, errors.Errorf("seccomp filter has %d instructions, more than the limit %d", len(prog), maxInsns)
+	}
+	return prog, nil
+}
+
+// rules 返回在当前环境中生效的规则，与默认处理方式相同的规则是多余的，被忽略
+func (p *Profile) rules(t *target, defaultAction uint32) ([]*rule, error) {
+	native := nativeArches[t.goarch].name
+	var rules []*rule
+	for _, call := range p.Syscalls {
+		if !call.applies(native, t) {
+			continue
+		}
+		action, err := actionValue(call.Action, call.ErrnoRet)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "syscall %s", call.names())
+		}
+		if action == defaultAction {
+			continue
+		}
+		rules = append(rules, &rule{names: call.names(), action: action, conditions: conditions(call.Args)})
+	}
+	return rules, nil
+}
+
+// applies 判断规则的 includes 和 excludes 在当前环境中是否满足。
+// includes 中的capabilities需要全部拥有，excludes 中的capabilities拥有任意一个时规则不生效
+func (s *Syscall) applies(native string, t *target) bool {
+	if f := s.Includes; f != nil {
+		if len(f.Arches) > 0 && !inSlice(f.Arches, native) {
+			return false
+		}
+		for _, c := range f.Caps {
+			if !inSlice(t.caps, c) {
+				return false
+			}
+		}
+		if f.MinKernel != "" {
+			if k, err := parseKernelVersion(f.MinKernel); err != nil || t.kernel.less(k) {
+				return false
+			}
+		}
+	}
+	if f := s.Excludes; f != nil {
+		if inSlice(f.Arches, native) {
+			return false
+		}
+		for _, c := range f.Caps {
+			if inSlice(t.caps, c) {
+				return false
+			}
+		}
+		if f.MinKernel != "" {
+			if k, err := parseKernelVersion(f.MinKernel); err == nil && !t.kernel.less(k) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// conditions 将参数条件分组。与 docker 一致，同一个参数出现多次时每个条件单独成为一组，满足任意一个即可；
+// 否则所有条件需要同时满足
+func conditions(args []*Arg) [][]*Arg {
+	seen := make(map[uint]bool)
+	for _, arg := range args {
+		if seen[arg.Index] {
+			groups := make([][]*Arg, 0, len(args))
+			for _, arg := range args {
+				groups = append(groups, []*Arg{arg})
+			}
+			return groups
+		}
+		seen[arg.Index] = true
+	}
+	return [][]*Arg{args}
+}
+
+// compileArch 生成一个架构的部分，进入时累加器中是架构
+func compileArch(arch Arch, rules []*rule, defaultAction uint32) ([]unix.SockFilter, error) {
+	info := supportedArches[arch]
+	prog := []unix.SockFilter{load(offsetNr)}
+	if arch == ArchX86_64 {
+		// 没有x32的系统调用表，x32的系统调用按照不支持的架构处理
+		prog = append(prog, jumpIf(unix.BPF_JSET, x32SyscallBit, 0, 1), ret(badArchAction))
+	}
+	for _, r := range rules {
+		for _, name := range r.names {
+			nr, ok := info.syscalls[name]
+			if !ok {
+				// 系统调用在这个架构上不存在
+				continue
+			}
+			for _, conds := range r.conditions {
+				if len(conds) == 0 {
+					prog = append(prog, jumpIf(unix.BPF_JEQ, uint32(nr), 0, 1), ret(r.action))
+					continue
+				}
+				checks := compileConditions(conds, info.is32Bit)
+				// 条件不满足时跳过返回指令，重新加载系统调用号后继续比较下一条规则
+				if len(checks)+2 > 0xff {
+					return nil, errors.Errorf("arguments of syscall %s are too complex", name)
+				}
+				prog = append(prog, jumpIf(unix.BPF_JEQ, uint32(nr), 0, uint8(len(checks)+2)))
+				prog = append(prog, checks...)
+				prog = append(prog, ret(r.action), load(offsetNr))
+			}
+		}
+	}
+	return append(prog, ret(defaultAction)), nil
+}
+
+// compileConditions 生成一组参数条件的比较，全部满足时执行到末尾，任意一个不满足时跳转到末尾之后的第二条指令
+func compileConditions(conds []*Arg, is32Bit bool) []unix.SockFilter {
+	var checks []unix.SockFilter
+	for _, arg := range conds {
+		checks = append(checks, compileCondition(arg, is32Bit)...)
+	}
+	for i := range checks {
+		// 末尾之后是返回指令，条件不满足时跳过它
+		fail := uint8(len(checks) - i)
+		if checks[i].Jt == failJump {
+			checks[i].Jt = fail
+		}
+		if checks[i].Jf == failJump {
+			checks[i].Jf = fail
+		}
+	}
+	return checks
+}
+
+// compileCondition 比较一个64位的参数，先比较高32位再比较低32位。32位的架构只比较低32位
+func compileCondition(arg *Arg, is32Bit bool) []unix.SockFilter {
+	lo := offsetArgs + 8*uint32(arg.Index)
+	hi := lo + 4
+	value, valueTwo := arg.Value, arg.ValueTwo
+	if is32Bit {
+		switch arg.Op {
+		case OpNotEqual:
+			return []unix.SockFilter{load(lo), jumpIf(unix.BPF_JEQ, uint32(value), failJump, 0)}
+		case OpLessThan:
+			return []unix.SockFilter{load(lo), jumpIf(unix.BPF_JGE, uint32(value), failJump, 0)}
+		case OpLessEqual:
+			return []unix.SockFilter{load(lo), jumpIf(unix.BPF_JGT, uint32(value), failJump, 0)}
+		case OpEqualTo:
+			return []unix.SockFilter{load(lo), jumpIf(unix.BPF_JEQ, uint32(value), 0, failJump)}
+		case OpGreaterEqual:
+			return []unix.SockFilter{load(lo), jumpIf(unix.BPF_JGE, uint32(value), 0, failJump)}
+		case OpGreaterThan:
+			return []unix.SockFilter{load(lo), jumpIf(unix.BPF_JGT, uint32(value), 0, failJump)}
+		case OpMaskedEqual:
+			return []unix.SockFilter{load(lo), and(uint32(value)), jumpIf(unix.BPF_JEQ, uint32(valueTwo), 0, failJump)}
+		}
+		return nil
+	}
+	vhi, vlo := uint32(value>>32), uint32(value)
+	switch arg.Op {
+	case OpNotEqual:
+		return []unix.SockFilter{
+			load(hi), jumpIf(unix.BPF_JEQ, vhi, 0, 2),
+			load(lo), jumpIf(unix.BPF_JEQ, vlo, failJump, 0),
+		}
+	case OpLessThan:
+		return []unix.SockFilter{
+			load(hi), jumpIf(unix.BPF_JGT, vhi, failJump, 0), jumpIf(unix.BPF_JEQ, vhi, 0, 2),
+			load(lo), jumpIf(unix.BPF_JGE, vlo, failJump, 0),
+		}
+	case OpLessEqual:
+		return []unix.SockFilter{
+			load(hi), jumpIf(unix.BPF_JGT, vhi, failJump, 0), jumpIf(unix.BPF_JEQ, vhi, 0, 2),
+			load(lo), jumpIf(unix.BPF_JGT, vlo, failJump, 0),
+		}
+	case OpEqualTo:
+		return []unix.SockFilter{
+			load(hi), jumpIf(unix.BPF_JEQ, vhi, 0, failJump),
+			load(lo), jumpIf(unix.BPF_JEQ, vlo, 0, failJump),
+		}
+	case OpGreaterEqual:
+		return []unix.SockFilter{
+			load(hi), jumpIf(unix.BPF_JGT, vhi, 3, 0), jumpIf(unix.BPF_JEQ, vhi, 0, failJump),
+			load(lo), jumpIf(unix.BPF_JGE, vlo, 0, failJump),
+		}
+	case OpGreaterThan:
+		return []unix.SockFilter{
+			load(hi), jumpIf(unix.BPF_JGT, vhi, 3, 0), jumpIf(unix.BPF_JEQ, vhi, 0, failJump),
+			load(lo), jumpIf(unix.BPF_JGT, vlo, 0, failJump),
+		}
+	case OpMaskedEqual:
+		return []unix.SockFilter{
+			load(hi), and(uint32(value >> 32)), jumpIf(unix.BPF_JEQ, uint32(valueTwo>>32), 0, failJump),
+			load(lo), and(uint32(value)), jumpIf(unix.BPF_JEQ, uint32(valueTwo), 0, failJump),
+		}
+	}
+	return nil
+}
+
+func load(offset uint32) unix.SockFilter {
+	return unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offset}
+}
+
+func and(k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: unix.BPF_ALU | unix.BPF_AND | unix.BPF_K, K: k}
+}
+
+func jumpIf(op uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: unix.BPF_JMP | op | unix.BPF_K, K: k, Jt: jt, Jf: jf}
+}
+
+func ret(k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: k}
+}
+
+func inSlice(slice []string, s string) bool {
+	for _, ss := range slice {
+		if ss == s {
+			return true
+		}
+	}
+	return false
+}
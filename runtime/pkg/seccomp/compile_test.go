@@ -0,0 +1,231 @@
+package seccomp
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccompData 对应内核的 struct seccomp_data
+type seccompData struct {
+	nr   int
+	arch uint32
+	args [maxArgs]uint64
+}
+
+// run 在用户态解释执行过滤器，返回过滤器的返回值
+func run(t *testing.T, prog []unix.SockFilter, data seccompData) uint32 {
+	t.Helper()
+	buf := make([]byte, offsetArgs+8*maxArgs)
+	binary.LittleEndian.PutUint32(buf[offsetNr:], uint32(data.nr))
+	binary.LittleEndian.PutUint32(buf[offsetArch:], data.arch)
+	for i, arg := range data.args {
+		binary.LittleEndian.PutUint64(buf[offsetArgs+8*i:], arg)
+	}
+	var a uint32
+	for pc := 0; pc < len(prog); pc++ {
+		insn := prog[pc]
+		switch insn.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			if int(insn.K)+4 > len(buf) {
+				t.Fatalf("load out of range at %d: %d", pc, insn.K)
+			}
+			a = binary.LittleEndian.Uint32(buf[insn.K:])
+		case unix.BPF_ALU | unix.BPF_AND | unix.BPF_K:
+			a &= insn.K
+		case unix.BPF_JMP | unix.BPF_JA:
+			pc += int(insn.K)
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			pc += branch(a == insn.K, insn)
+		case unix.BPF_JMP | unix.BPF_JGT | unix.BPF_K:
+			pc += branch(a > insn.K, insn)
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			pc += branch(a >= insn.K, insn)
+		case unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K:
+			pc += branch(a&insn.K != 0, insn)
+		case unix.BPF_RET | unix.BPF_K:
+			return insn.K
+		default:
+			t.Fatalf("unexpected instruction at %d: %+v", pc, insn)
+		}
+	}
+	t.Fatalf("filter fell off the end")
+	return 0
+}
+
+func branch(cond bool, insn unix.SockFilter) int {
+	if cond {
+		return int(insn.Jt)
+	}
+	return int(insn.Jf)
+}
+
+func compileFor(t *testing.T, profile string, goarch string, caps []string, kernel KernelVersion) []unix.SockFilter {
+	t.Helper()
+	p, err := ParseProfile([]byte(profile))
+	if err != nil {
+		t.Fatalf("parse profile: %v", err)
+	}
+	prog, err := p.compile(&target{goarch: goarch, caps: caps, kernel: kernel})
+	if err != nil {
+		t.Fatalf("compile profile: %v", err)
+	}
+	if len(prog) > maxInsns {
+		t.Fatalf("filter has %d instructions", len(prog))
+	}
+	return prog
+}
+
+const cannedProfile = `{
+	"defaultAction": "SCMP_ACT_ERRNO",
+	"architectures": ["SCMP_ARCH_X86_64", "SCMP_ARCH_X86"],
+	"syscalls": [
+		{"names": ["read", "write"], "action": "SCMP_ACT_ALLOW"},
+		{"name": "kill", "action": "SCMP_ACT_ERRNO", "errnoRet": 13},
+		{"names": ["getpid"], "action": "SCMP_ACT_KILL_PROCESS"},
+		{"names": ["personality"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 8, "op": "SCMP_CMP_EQ"}]},
+		{"names": ["lseek"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 2, "value": 2, "op": "SCMP_CMP_LE"}]},
+		{"names": ["mmap"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 1, "value": 4294967296, "op": "SCMP_CMP_GT"}]},
+		{"names": ["socket"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 40, "op": "SCMP_CMP_NE"}]},
+		{"names": ["clone"], "action": "SCMP_ACT_ALLOW", "args": [{"index": 0, "value": 2114060288, "valueTwo": 0, "op": "SCMP_CMP_MASKED_EQ"}]},
+		{"names": ["fcntl"], "action": "SCMP_ACT_ALLOW", "args": [
+			{"index": 1, "value": 1, "op": "SCMP_CMP_EQ"},
+			{"index": 1, "value": 3, "op": "SCMP_CMP_EQ"}
+		]},
+		{"names": ["pread64"], "action": "SCMP_ACT_ALLOW", "args": [
+			{"index": 0, "value": 3, "op": "SCMP_CMP_GE"},
+			{"index": 2, "value": 100, "op": "SCMP_CMP_LT"}
+		]},
+		{"names": ["no_such_syscall"], "action": "SCMP_ACT_ALLOW"}
+	]
+}`
+
+func TestCompileCannedProfile(t *testing.T) {
+	prog := compileFor(t, cannedProfile, "amd64", nil, KernelVersion{Kernel: 6, Major: 1})
+	eperm := retErrno | uint32(unix.EPERM)
+	x86_64 := uint32(unix.AUDIT_ARCH_X86_64)
+
+	tests := []struct {
+		name string
+		nr   int
+		arch uint32
+		args [maxArgs]uint64
+		want uint32
+	}{
+		{"allowed", unix.SYS_READ, x86_64, [maxArgs]uint64{}, retAllow},
+		{"default action", unix.SYS_OPEN, x86_64, [maxArgs]uint64{}, eperm},
+		{"errnoRet", unix.SYS_KILL, x86_64, [maxArgs]uint64{}, retErrno | 13},
+		{"kill process", unix.SYS_GETPID, x86_64, [maxArgs]uint64{}, retKillProcess},
+		{"eq match", unix.SYS_PERSONALITY, x86_64, [maxArgs]uint64{8}, retAllow},
+		{"eq high word differs", unix.SYS_PERSONALITY, x86_64, [maxArgs]uint64{1<<32 | 8}, eperm},
+		{"le equal", unix.SYS_LSEEK, x86_64, [maxArgs]uint64{0, 0, 2}, retAllow},
+		{"le greater", unix.SYS_LSEEK, x86_64, [maxArgs]uint64{0, 0, 3}, eperm},
+		{"le high word", unix.SYS_LSEEK, x86_64, [maxArgs]uint64{0, 0, 1 << 32}, eperm},
+		{"gt high word", unix.SYS_MMAP, x86_64, [maxArgs]uint64{0, 1<<32 + 1}, retAllow},
+		{"gt equal", unix.SYS_MMAP, x86_64, [maxArgs]uint64{0, 1 << 32}, eperm},
+		{"gt smaller", unix.SYS_MMAP, x86_64, [maxArgs]uint64{0, 4096}, eperm},
+		{"ne match", unix.SYS_SOCKET, x86_64, [maxArgs]uint64{unix.AF_INET}, retAllow},
+		{"ne equal", unix.SYS_SOCKET, x86_64, [maxArgs]uint64{unix.AF_VSOCK}, eperm},
+		{"masked eq match", unix.SYS_CLONE, x86_64, [maxArgs]uint64{unix.CLONE_VM | unix.CLONE_THREAD}, retAllow},
+		{"masked eq mismatch", unix.SYS_CLONE, x86_64, [maxArgs]uint64{unix.CLONE_NEWNS}, eperm},
+		{"same index first", unix.SYS_FCNTL, x86_64, [maxArgs]uint64{0, 1}, retAllow},
+		{"same index second", unix.SYS_FCNTL, x86_64, [maxArgs]uint64{0, 3}, retAllow},
+		{"same index neither", unix.SYS_FCNTL, x86_64, [maxArgs]uint64{0, 2}, eperm},
+		{"and both", unix.SYS_PREAD64, x86_64, [maxArgs]uint64{3, 0, 99}, retAllow},
+		{"and first fails", unix.SYS_PREAD64, x86_64, [maxArgs]uint64{2, 0, 99}, eperm},
+		{"and second fails", unix.SYS_PREAD64, x86_64, [maxArgs]uint64{3, 0, 100}, eperm},
+		{"x86 subarch", syscallsX86["write"], unix.AUDIT_ARCH_I386, [maxArgs]uint64{}, retAllow},
+		{"x86 subarch default", syscallsX86["open"], unix.AUDIT_ARCH_I386, [maxArgs]uint64{}, eperm},
+		{"x86 only compares low word", syscallsX86["personality"], unix.AUDIT_ARCH_I386, [maxArgs]uint64{1<<32 | 8}, retAllow},
+		{"x32 syscall", x32SyscallBit | unix.SYS_READ, x86_64, [maxArgs]uint64{}, badArchAction},
+		{"unlisted arch", unix.SYS_READ, unix.AUDIT_ARCH_AARCH64, [maxArgs]uint64{}, badArchAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := run(t, prog, seccompData{nr: tt.nr, arch: tt.arch, args: tt.args})
+			if got != tt.want {
+				t.Errorf("got %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileDefaultProfile(t *testing.T) {
+	defaultCaps := []string{"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_FOWNER", "CAP_SETUID", "CAP_SETGID", "CAP_KILL"}
+	eperm := retErrno | uint32(unix.EPERM)
+	tests := []struct {
+		name   string
+		goarch string
+		caps   []string
+		kernel KernelVersion
+		arch   uint32
+		nr     int
+		args   [maxArgs]uint64
+		want   uint32
+	}{
+		{"read", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["read"], [maxArgs]uint64{}, retAllow},
+		{"arch_prctl", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["arch_prctl"], [maxArgs]uint64{}, retAllow},
+		{"mount without CAP_SYS_ADMIN", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["mount"], [maxArgs]uint64{}, eperm},
+		{"mount with CAP_SYS_ADMIN", "amd64", append(defaultCaps, "CAP_SYS_ADMIN"), KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["mount"], [maxArgs]uint64{}, retAllow},
+		{"fork via clone", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["clone"], [maxArgs]uint64{uint64(unix.SIGCHLD)}, retAllow},
+		{"clone new namespace", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["clone"], [maxArgs]uint64{unix.CLONE_NEWUSER}, eperm},
+		{"clone3 falls back", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["clone3"], [maxArgs]uint64{}, retErrno | uint32(unix.ENOSYS)},
+		{"personality", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["personality"], [maxArgs]uint64{0xffffffff}, retAllow},
+		{"vsock", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["socket"], [maxArgs]uint64{unix.AF_VSOCK}, eperm},
+		{"ptrace on new kernel", "amd64", defaultCaps, KernelVersion{4, 8}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["ptrace"], [maxArgs]uint64{}, retAllow},
+		{"ptrace on old kernel", "amd64", defaultCaps, KernelVersion{4, 4}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["ptrace"], [maxArgs]uint64{}, eperm},
+		{"i386 socket", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_I386, syscallsX86["socket"], [maxArgs]uint64{unix.AF_INET}, retAllow},
+		{"i386 reboot", "amd64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_I386, syscallsX86["reboot"], [maxArgs]uint64{}, eperm},
+		{"arm64 openat", "arm64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_AARCH64, syscallsAARCH64["openat"], [maxArgs]uint64{}, retAllow},
+		{"arm64 reboot", "arm64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_AARCH64, syscallsAARCH64["reboot"], [maxArgs]uint64{}, eperm},
+		{"arm on arm64", "arm64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_ARM, syscallsARM["arm_fadvise64_64"], [maxArgs]uint64{}, retAllow},
+		{"x86 on arm64", "arm64", defaultCaps, KernelVersion{6, 1}, unix.AUDIT_ARCH_X86_64, syscallsX86_64["read"], [maxArgs]uint64{}, badArchAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := DefaultProfile().compile(&target{goarch: tt.goarch, caps: tt.caps, kernel: tt.kernel})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(prog) > maxInsns {
+				t.Fatalf("filter has %d instructions", len(prog))
+			}
+			got := run(t, prog, seccompData{nr: tt.nr, arch: tt.arch, args: tt.args})
+			if got != tt.want {
+				t.Errorf("got %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown default action": `{"defaultAction": "SCMP_ACT_NOPE", "syscalls": []}`,
+		"unknown action":         `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_NOPE"}]}`,
+		"unknown operator":       `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_ERRNO", "args": [{"index": 0, "op": "SCMP_CMP_NOPE"}]}]}`,
+		"argument index":         `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_ERRNO", "args": [{"index": 6, "op": "SCMP_CMP_EQ"}]}]}`,
+		"name and names":         `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"name": "read", "names": ["write"], "action": "SCMP_ACT_ERRNO"}]}`,
+		"errnoRet with allow":    `{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_ALLOW", "errnoRet": 1}]}`,
+		"unknown architecture":   `{"defaultAction": "SCMP_ACT_ALLOW", "architectures": ["SCMP_ARCH_NOPE"], "syscalls": []}`,
+		"invalid minKernel":      `{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_ERRNO", "includes": {"minKernel": "four"}}]}`,
+		"invalid json":           `{"defaultAction": `,
+	}
+	for name, profile := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseProfile([]byte(profile)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestCompileNativeArchNotListed(t *testing.T) {
+	p, err := ParseProfile([]byte(`{"defaultAction": "SCMP_ACT_ERRNO", "architectures": ["SCMP_ARCH_AARCH64"], "syscalls": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.compile(&target{goarch: "amd64"}); err == nil {
+		t.Error("expected an error for a profile without the native architecture")
+	}
+}
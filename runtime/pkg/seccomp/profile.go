@@ -0,0 +1,174 @@
+package seccomp
+
+import (
+	_ "embed"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Action 是系统调用匹配规则时的处理方式
+type Action string
+
+const (
+	ActKill        Action = "SCMP_ACT_KILL"
+	ActKillProcess Action = "SCMP_ACT_KILL_PROCESS"
+	ActKillThread  Action = "SCMP_ACT_KILL_THREAD"
+	ActTrap        Action = "SCMP_ACT_TRAP"
+	ActErrno       Action = "SCMP_ACT_ERRNO"
+	ActTrace       Action = "SCMP_ACT_TRACE"
+	ActAllow       Action = "SCMP_ACT_ALLOW"
+	ActLog         Action = "SCMP_ACT_LOG"
+)
+
+// Operator 是系统调用参数的比较方式
+type Operator string
+
+const (
+	OpNotEqual     Operator = "SCMP_CMP_NE"
+	OpLessThan     Operator = "SCMP_CMP_LT"
+	OpLessEqual    Operator = "SCMP_CMP_LE"
+	OpEqualTo      Operator = "SCMP_CMP_EQ"
+	OpGreaterEqual Operator = "SCMP_CMP_GE"
+	OpGreaterThan  Operator = "SCMP_CMP_GT"
+	OpMaskedEqual  Operator = "SCMP_CMP_MASKED_EQ"
+)
+
+// Profile 是与 docker 兼容的seccomp配置
+type Profile struct {
+	DefaultAction   Action         `json:"defaultAction"`
+	DefaultErrnoRet *uint          `json:"defaultErrnoRet,omitempty"`
+	Architectures   []Arch         `json:"architectures,omitempty"`
+	ArchMap         []Architecture `json:"archMap,omitempty"`
+	Syscalls        []*Syscall     `json:"syscalls"`
+}
+
+// Architecture 是一个主架构以及在它上面同样可以运行的子架构
+type Architecture struct {
+	Arch      Arch   `json:"architecture"`
+	SubArches []Arch `json:"subArchitectures"`
+}
+
+// Filter 根据capabilities、架构和内核版本决定规则是否生效
+type Filter struct {
+	Caps   []string `json:"caps,omitempty"`
+	Arches []string `json:"arches,omitempty"`
+	// MinKernel 形如 4.8
+	MinKernel string `json:"minKernel,omitempty"`
+}
+
+// Syscall 是一条规则：名称为Names或Name的系统调用在参数满足Args时按照Action处理
+type Syscall struct {
+	Name     string   `json:"name,omitempty"`
+	Names    []string `json:"names,omitempty"`
+	Action   Action   `json:"action"`
+	ErrnoRet *uint    `json:"errnoRet,omitempty"`
+	Args     []*Arg   `json:"args"`
+	Comment  string   `json:"comment"`
+	Includes *Filter  `json:"includes,omitempty"`
+	Excludes *Filter  `json:"excludes,omitempty"`
+}
+
+// Arg 是对系统调用第Index个参数的比较，SCMP_CMP_MASKED_EQ 时Value是掩码，ValueTwo是期望的值
+type Arg struct {
+	Index    uint     `json:"index"`
+	Value    uint64   `json:"value"`
+	ValueTwo uint64   `json:"valueTwo"`
+	Op       Operator `json:"op"`
+}
+
+//go:embed default.json
+var defaultProfile []byte
+
+// DefaultProfile 返回内置的默认配置，与 docker 的默认配置相同：只允许常用的系统调用，
+// 需要特定capability的系统调用只在容器拥有该capability时允许
+func DefaultProfile() *Profile {
+	p, err := ParseProfile(defaultProfile)
+	if err != nil {
+		panic(err) // This shouldn't happen
+	}
+	return p
+}
+
+// LoadProfile 读取并解析path处的配置文件
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("read seccomp profile %s error %v", path, err)
+	}
+	return ParseProfile(data)
+}
+
+// ParseProfile 解析并校验JSON格式的配置
+func ParseProfile(data []byte) (*Profile, error) {
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, errors.Errorf("decode seccomp profile error %v", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Profile) validate() error {
+	if _, err := actionValue(p.DefaultAction, p.DefaultErrnoRet); err != nil {
+		return errors.WithMessage(err, "invalid defaultAction")
+	}
+	if len(p.Architectures) > 0 && len(p.ArchMap) > 0 {
+		return errors.New("'architectures' and 'archMap' were specified in the seccomp profile, use either 'architectures' or 'archMap'")
+	}
+	for _, a := range p.Architectures {
+		if _, ok := knownArches[a]; !ok {
+			return errors.Errorf("unknown architecture %q", a)
+		}
+	}
+	for _, m := range p.ArchMap {
+		for _, a := range append([]Arch{m.Arch}, m.SubArches...) {
+			if _, ok := knownArches[a]; !ok {
+				return errors.Errorf("unknown architecture %q", a)
+			}
+		}
+	}
+	for _, call := range p.Syscalls {
+		if call.Name != "" && len(call.Names) > 0 {
+			return errors.New("'name' and 'names' were specified in the seccomp profile, use either 'name' or 'names'")
+		}
+		if _, err := actionValue(call.Action, call.ErrnoRet); err != nil {
+			return errors.WithMessagef(err, "invalid action of syscall %s", call.names())
+		}
+		for _, arg := range call.Args {
+			if arg.Index >= maxArgs {
+				return errors.Errorf("syscall %s has argument index %d, only %d arguments are supported", call.names(), arg.Index, maxArgs)
+			}
+			if !arg.Op.valid() {
+				return errors.Errorf("unknown operator %q of syscall %s", arg.Op, call.names())
+			}
+		}
+		for _, f := range []*Filter{call.Includes, call.Excludes} {
+			if f == nil || f.MinKernel == "" {
+				continue
+			}
+			if _, err := parseKernelVersion(f.MinKernel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Syscall) names() []string {
+	if s.Name != "" {
+		return []string{s.Name}
+	}
+	return s.Names
+}
+
+func (op Operator) valid() bool {
+	switch op {
+	case OpNotEqual, OpLessThan, OpLessEqual, OpEqualTo, OpGreaterEqual, OpGreaterThan, OpMaskedEqual:
+		return true
+	}
+	return false
+}
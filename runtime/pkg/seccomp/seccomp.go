@@ -0,0 +1,21 @@
+package seccomp
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Load 为当前线程加载过滤器，之后执行的程序继承过滤器。没有设置no_new_privs时当前线程需要拥有 CAP_SYS_ADMIN。
+// 调用者需要先 runtime.LockOSThread，并在同一个线程上执行用户命令
+func Load(filter []unix.SockFilter) error {
+	if len(filter) == 0 {
+		return errors.New("empty seccomp filter")
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0); err != nil {
+		return errors.Errorf("load seccomp filter error %v", err)
+	}
+	return nil
+}
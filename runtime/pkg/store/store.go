@@ -0,0 +1,205 @@
+// Package store 持久化以ID区分的JSON状态文件。每个对象占用根目录下的一个子目录，
+// 修改前对子目录加flock排他锁，写入时先写临时文件并fsync，再rename覆盖原文件，
+// 保证并发修改不会互相覆盖，主机崩溃后也不会留下被截断的文件
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Store 是保存在root目录下的状态文件集合，每个对象的状态保存在 root/<id>/<fileName>
+type Store struct {
+	root     string
+	fileName string
+}
+
+// New 创建一个Store
+func New(root, fileName string) *Store {
+	return &Store{root: root, fileName: fileName}
+}
+
+// Dir 返回对象的目录
+func (s *Store) Dir(id string) string {
+	return filepath.Join(s.root, id)
+}
+
+// Path 返回对象状态文件的路径
+func (s *Store) Path(id string) string {
+	return filepath.Join(s.root, id, s.fileName)
+}
+
+// Exists 判断对象的状态文件是否存在
+func (s *Store) Exists(id string) bool {
+	if !validID(id) {
+		return false
+	}
+	_, err := os.Stat(s.Path(id))
+	return err == nil
+}
+
+// List 返回所有保存了状态文件的对象ID
+func (s *Store) List() ([]string, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.Errorf("read dir %s error %v", s.root, err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && s.Exists(entry.Name()) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+// Read 读取对象的状态到v中。状态文件总是被整体替换，读取时不需要加锁
+func (s *Store) Read(id string, v any) error {
+	if !validID(id) {
+		return errors.Wrapf(os.ErrNotExist, "invalid id %q", id)
+	}
+	content, err := os.ReadFile(s.Path(id))
+	if err != nil {
+		return errors.Wrapf(err, "read file %s", s.Path(id))
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return errors.Wrapf(err, "decode %s", s.Path(id))
+	}
+	return nil
+}
+
+// Write 加锁后原子地写入对象的状态，对象目录不存在时创建
+func (s *Store) Write(id string, v any) error {
+	if !validID(id) {
+		return errors.Errorf("invalid id %q", id)
+	}
+	if err := os.MkdirAll(s.Dir(id), 0o755); err != nil {
+		return errors.WithMessagef(err, "mkdir %s failed", s.Dir(id))
+	}
+	unlock, err := s.Lock(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.write(id, v)
+}
+
+// Update 加锁后读取对象的最新状态到v中，调用fn修改后原子地写回。
+// fn返回ErrSkip时不写回，返回其他错误时Update返回该错误
+func (s *Store) Update(id string, v any, fn func() error) error {
+	if !validID(id) {
+		return errors.Wrapf(os.ErrNotExist, "invalid id %q", id)
+	}
+	unlock, err := s.Lock(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.Read(id, v); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if errors.Is(err, ErrSkip) {
+			return nil
+		}
+		return err
+	}
+	return s.write(id, v)
+}
+
+// Delete 加锁后删除对象的目录
+func (s *Store) Delete(id string) error {
+	if !validID(id) {
+		return errors.Errorf("invalid id %q", id)
+	}
+	unlock, err := s.Lock(id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer unlock()
+	if err := os.RemoveAll(s.Dir(id)); err != nil {
+		return errors.Errorf("Remove dir %s error: %v", s.Dir(id), err)
+	}
+	return nil
+}
+
+// ErrSkip 由Update的回调返回，表示不需要写回状态
+var ErrSkip = errors.New("skip update")
+
+// Lock 对对象的目录加flock排他锁，返回解锁函数。锁在进程退出时由内核自动释放
+func (s *Store) Lock(id string) (func(), error) {
+	dir, err := os.Open(s.Dir(id))
+	if err != nil {
+		return nil, errors.Wrapf(err, "open %s", s.Dir(id))
+	}
+	if err := unix.Flock(int(dir.Fd()), unix.LOCK_EX); err != nil {
+		_ = dir.Close()
+		return nil, errors.WithMessagef(err, "lock %s", s.Dir(id))
+	}
+	return func() {
+		_ = unix.Flock(int(dir.Fd()), unix.LOCK_UN)
+		_ = dir.Close()
+	}, nil
+}
+
+// write 将状态写入同目录下的临时文件并fsync，然后rename覆盖状态文件，最后fsync目录使rename持久化
+func (s *Store) write(id string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithMessage(err, "marshal state failed")
+	}
+	dir := s.Dir(id)
+	tmp, err := os.CreateTemp(dir, "."+s.fileName+"-")
+	if err != nil {
+		return errors.WithMessagef(err, "create temp file in %s failed", dir)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return errors.WithMessagef(err, "write state to file %s failed", tmpName)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return errors.WithMessagef(err, "chmod %s failed", tmpName)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return errors.WithMessagef(err, "fsync %s failed", tmpName)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithMessagef(err, "close %s failed", tmpName)
+	}
+	if err := os.Rename(tmpName, s.Path(id)); err != nil {
+		return errors.WithMessagef(err, "rename %s failed", tmpName)
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.WithMessagef(err, "open %s failed", dir)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return errors.WithMessagef(err, "fsync %s failed", dir)
+	}
+	return nil
+}
+
+// validID 拒绝可能逃逸出根目录的ID
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsRune(id, '/')
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+type counter struct {
+	Count int `json:"count"`
+}
+
+func TestStoreUpdate(t *testing.T) {
+	s := New(t.TempDir(), "config.json")
+	if err := s.Write("c1", &counter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := new(counter)
+			if err := s.Update("c1", c, func() error {
+				c.Count++
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c := new(counter)
+	if err := s.Read("c1", c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Count != 20 {
+		t.Errorf("expected count 20 but %d got", c.Count)
+	}
+
+	if err := s.Update("c1", c, func() error { c.Count = 0; return ErrSkip }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Read("c1", c); err != nil || c.Count != 20 {
+		t.Errorf("skipped update should not be written, got %d %v", c.Count, err)
+	}
+
+	entries, _ := os.ReadDir(s.Dir("c1"))
+	if len(entries) != 1 {
+		t.Errorf("expected only the state file to be left, got %d entries", len(entries))
+	}
+
+	if err := s.Delete("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Exists("c1") {
+		t.Error("expected c1 to be deleted")
+	}
+	if err := s.Read("../c1", c); err == nil {
+		t.Error("expected invalid id to be rejected")
+	}
+}
@@ -12,9 +12,14 @@ const (
 )
 
 const (
-	InfoLoc       = "/var/lib/sudocker/containers/"
-	InfoLocFormat = InfoLoc + "%s/"
-	ConfigName    = "config.json"
-	IDLength      = 10
-	LogFile       = "%s-json.log"
+	InfoLoc        = "/var/lib/sudocker/containers/"
+	InfoLocFormat  = InfoLoc + "%s/"
+	ConfigName     = "config.json"
+	ExecFifoName   = "exec.fifo"
+	MonitorLogName = "monitor.log"
+	MonitorSock    = "monitor.sock"
+	NamesLoc       = "/var/lib/sudocker/names/"
+	IDLength       = 64
+	ShortIDLength  = 12
+	LogFile        = "%s-json.log"
 )
@@ -331,6 +331,11 @@ func GetRoot(containerId string) string { return RootPath + containerId }
 
 func GetImage(imageName string) string { return fmt.Sprintf("%s%s.tar", ImagePath, imageName) }
 
+// GetImageConfig 返回镜像运行配置文件的路径，与镜像的tar包在同一目录
+func GetImageConfig(imageName string) string {
+	return fmt.Sprintf("%s%s.json", ImagePath, imageName)
+}
+
 func GetLower(containerId string) string {
 	return fmt.Sprintf(lowerDirFormat, containerId)
 }